@@ -0,0 +1,418 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// arrayID is the id of an array stored with op_put_slice, passed as an ARRAY parameter.
+type arrayID []byte
+
+// arrayDesc describes an ARRAY column by RDB$FIELDS and RDB$FIELD_DIMENSIONS.
+type arrayDesc struct {
+	relname   string
+	fieldname string
+	element   xSQLVAR
+	lower     []int
+	upper     []int
+}
+
+// RDB$FIELD_TYPE to the type of the array element
+var arrayElementTypes = map[int]int{
+	7:  SQL_TYPE_SHORT,
+	8:  SQL_TYPE_LONG,
+	16: SQL_TYPE_INT64,
+	10: SQL_TYPE_FLOAT,
+	27: SQL_TYPE_DOUBLE,
+	12: SQL_TYPE_DATE,
+	13: SQL_TYPE_TIME,
+	35: SQL_TYPE_TIMESTAMP,
+	14: SQL_TYPE_TEXT,
+	37: SQL_TYPE_VARYING,
+	23: SQL_TYPE_BOOLEAN,
+}
+
+// BLR type of the parameter which has the XDR format of the array element
+var arrayElementBlr = map[int]byte{
+	SQL_TYPE_SHORT:     8,
+	SQL_TYPE_LONG:      8,
+	SQL_TYPE_INT64:     16,
+	SQL_TYPE_DATE:      12,
+	SQL_TYPE_TIME:      13,
+	SQL_TYPE_TIMESTAMP: 35,
+	SQL_TYPE_BOOLEAN:   23,
+}
+
+const arrayDescQuery = `SELECT F.RDB$FIELD_TYPE, F.RDB$FIELD_SCALE, F.RDB$FIELD_LENGTH,
+    F.RDB$CHARACTER_SET_ID, D.RDB$LOWER_BOUND, D.RDB$UPPER_BOUND
+FROM RDB$RELATION_FIELDS R
+JOIN RDB$FIELDS F ON F.RDB$FIELD_NAME = R.RDB$FIELD_SOURCE
+JOIN RDB$FIELD_DIMENSIONS D ON D.RDB$FIELD_NAME = F.RDB$FIELD_NAME
+WHERE R.RDB$RELATION_NAME = ? AND R.RDB$FIELD_NAME = ?
+ORDER BY D.RDB$DIMENSION`
+
+func metadataInt(v driver.Value) int {
+	switch n := v.(type) {
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	}
+	return 0
+}
+
+// arrayDesc returns the descriptor of the ARRAY column x, reading the metadata at the first use.
+func (stmt *firebirdsqlStmt) arrayDesc(x *xSQLVAR) (*arrayDesc, error) {
+	key := x.relname + "." + x.fieldname
+	if desc, ok := stmt.arrayDescs[key]; ok {
+		return desc, nil
+	}
+	if x.relname == "" || x.fieldname == "" {
+		return nil, fmt.Errorf("Can not find the table of array %s", x.aliasname)
+	}
+
+	s, err := newFirebirdsqlStmt(stmt.tx.fc, stmt.tx, arrayDescQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer s.free(2) // DSQL_drop
+	s.fixedTx = true
+	rows, err := s.query(context.Background(), []driver.Value{x.relname, x.fieldname})
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &arrayDesc{relname: x.relname, fieldname: x.fieldname}
+	dest := make([]driver.Value, 6)
+	for {
+		if err = rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		sqltype, ok := arrayElementTypes[metadataInt(dest[0])]
+		if !ok {
+			return nil, fmt.Errorf("Unsupported array element type %d of %s.%s", metadataInt(dest[0]), x.relname, x.fieldname)
+		}
+		desc.element = xSQLVAR{
+			sqltype:    sqltype,
+			sqlscale:   metadataInt(dest[1]),
+			sqllen:     metadataInt(dest[2]),
+			sqlsubtype: metadataInt(dest[3]),
+		}
+		desc.lower = append(desc.lower, metadataInt(dest[4]))
+		desc.upper = append(desc.upper, metadataInt(dest[5]))
+	}
+	if len(desc.lower) == 0 {
+		return nil, fmt.Errorf("%s.%s is not an array", x.relname, x.fieldname)
+	}
+
+	if stmt.arrayDescs == nil {
+		stmt.arrayDescs = make(map[string]*arrayDesc)
+	}
+	stmt.arrayDescs[key] = desc
+	return desc, nil
+}
+
+// count returns the number of the elements.
+func (d *arrayDesc) count() int {
+	n := 1
+	for i := range d.lower {
+		n *= d.upper[i] - d.lower[i] + 1
+	}
+	return n
+}
+
+// sliceLength returns the length in bytes of the elements in the server's memory layout.
+func (d *arrayDesc) sliceLength() int {
+	var ln int
+	switch d.element.sqltype {
+	case SQL_TYPE_TEXT:
+		ln = d.element.sqllen
+	case SQL_TYPE_VARYING:
+		ln = d.element.sqllen + 2
+	case SQL_TYPE_SHORT:
+		ln = 2
+	case SQL_TYPE_BOOLEAN:
+		ln = 1
+	default:
+		ln = xsqlvarTypeLength[d.element.sqltype]
+	}
+	return d.count() * ln
+}
+
+func sdlLiteral(n int) []byte {
+	switch {
+	case n >= -128 && n <= 127:
+		return []byte{isc_sdl_tiny_integer, byte(n)}
+	case n >= -32768 && n <= 32767:
+		return []byte{isc_sdl_short_integer, byte(n), byte(n >> 8)}
+	}
+	return []byte{isc_sdl_long_integer, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+}
+
+// sdl returns the slice description language of the whole array.
+func (d *arrayDesc) sdl() []byte {
+	x := &d.element
+	sdl := []byte{isc_sdl_version1, isc_sdl_struct, 1}
+	switch x.sqltype {
+	case SQL_TYPE_SHORT:
+		sdl = append(sdl, 7, byte(x.sqlscale))
+	case SQL_TYPE_LONG:
+		sdl = append(sdl, 8, byte(x.sqlscale))
+	case SQL_TYPE_INT64:
+		sdl = append(sdl, 16, byte(x.sqlscale))
+	case SQL_TYPE_FLOAT:
+		sdl = append(sdl, 10)
+	case SQL_TYPE_DOUBLE:
+		sdl = append(sdl, 27)
+	case SQL_TYPE_DATE:
+		sdl = append(sdl, 12)
+	case SQL_TYPE_TIME:
+		sdl = append(sdl, 13)
+	case SQL_TYPE_TIMESTAMP:
+		sdl = append(sdl, 35)
+	case SQL_TYPE_TEXT: // blr_text2
+		sdl = append(sdl, 15, byte(x.sqlsubtype), byte(x.sqlsubtype>>8), byte(x.sqllen), byte(x.sqllen>>8))
+	case SQL_TYPE_VARYING: // blr_varying2
+		sdl = append(sdl, 38, byte(x.sqlsubtype), byte(x.sqlsubtype>>8), byte(x.sqllen), byte(x.sqllen>>8))
+	case SQL_TYPE_BOOLEAN:
+		sdl = append(sdl, 23)
+	}
+	sdl = append(sdl, isc_sdl_relation, byte(len(d.relname)))
+	sdl = append(sdl, d.relname...)
+	sdl = append(sdl, isc_sdl_field, byte(len(d.fieldname)))
+	sdl = append(sdl, d.fieldname...)
+	for i := range d.lower {
+		if d.lower[i] == 1 {
+			sdl = append(sdl, isc_sdl_do1, byte(i))
+		} else {
+			sdl = append(sdl, isc_sdl_do2, byte(i))
+			sdl = append(sdl, sdlLiteral(d.lower[i])...)
+		}
+		sdl = append(sdl, sdlLiteral(d.upper[i])...)
+	}
+	sdl = append(sdl, isc_sdl_element, 1, isc_sdl_scalar, 0, byte(len(d.lower)))
+	for i := range d.lower {
+		sdl = append(sdl, isc_sdl_variable, byte(i))
+	}
+	return append(sdl, isc_sdl_eoc)
+}
+
+// toSlice arranges the elements into nested slices, one level for each dimension.
+func (d *arrayDesc) toSlice(elements []interface{}) (interface{}, error) {
+	if len(elements) != d.count() {
+		return nil, fmt.Errorf("Array %s has %d elements, got %d", d.fieldname, d.count(), len(elements))
+	}
+	t := reflect.TypeOf(elements[0])
+	for range d.lower {
+		t = reflect.SliceOf(t)
+	}
+	return d.makeSlice(t, 0, elements).Interface(), nil
+}
+
+func (d *arrayDesc) makeSlice(t reflect.Type, dim int, elements []interface{}) reflect.Value {
+	n := d.upper[dim] - d.lower[dim] + 1
+	size := len(elements) / n
+	s := reflect.MakeSlice(t, n, n)
+	for i := 0; i < n; i++ {
+		part := elements[i*size : (i+1)*size]
+		if dim == len(d.lower)-1 {
+			s.Index(i).Set(reflect.ValueOf(part[0]))
+		} else {
+			s.Index(i).Set(d.makeSlice(t.Elem(), dim+1, part))
+		}
+	}
+	return s
+}
+
+// flatten returns the elements of nested slices in the order of the array.
+func (d *arrayDesc) flatten(v reflect.Value, dim int, elements []interface{}) ([]interface{}, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Array %s needs a slice in dimension %d, got %v", d.fieldname, dim+1, v.Kind())
+	}
+	n := d.upper[dim] - d.lower[dim] + 1
+	if v.Len() != n {
+		return nil, fmt.Errorf("Array %s needs %d elements in dimension %d, got %d", d.fieldname, n, dim+1, v.Len())
+	}
+	var err error
+	for i := 0; i < n; i++ {
+		if dim == len(d.lower)-1 {
+			elements = append(elements, v.Index(i).Interface())
+		} else if elements, err = d.flatten(v.Index(i), dim+1, elements); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// isArrayValue reports whether v is a Go slice to be bound to an ARRAY parameter.
+func isArrayValue(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		return true
+	}
+	return false
+}
+
+// arrayElementToBytes encodes an element in the XDR format of op_put_slice.
+func (p *wireProtocol) arrayElementToBytes(transHandle int32, param interface{}, x *xSQLVAR) ([]byte, error) {
+	switch x.sqltype {
+	case SQL_TYPE_TEXT, SQL_TYPE_VARYING:
+		var b []byte
+		switch s := param.(type) {
+		case string:
+			var err error
+			if b, err = encodeCharset(s, x.columnCharset("NONE")); err != nil {
+				return nil, err
+			}
+		case []byte:
+			b = s
+		default:
+			return nil, fmt.Errorf("Can not convert %T to an array element of %s", param, x.typename())
+		}
+		if len(b) > x.sqllen {
+			return nil, fmt.Errorf("Value %q is longer than the array element of %d bytes", b, x.sqllen)
+		}
+		if x.sqltype == SQL_TYPE_VARYING {
+			_, v := _bytesToBlr(b)
+			return append(bint32_to_bytes(int32(len(b))), v...), nil
+		}
+		pad := byte(' ')
+		if x.sqlsubtype == 1 { // OCTETS
+			pad = 0
+		}
+		_, v := _bytesToBlr(append(b, bytes.Repeat([]byte{pad}, x.sqllen-len(b))...))
+		return v, nil
+	case SQL_TYPE_FLOAT, SQL_TYPE_DOUBLE:
+		var f float64
+		rv := reflect.ValueOf(param)
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		default:
+			return nil, fmt.Errorf("Can not convert %T to an array element of %s", param, x.typename())
+		}
+		if x.sqltype == SQL_TYPE_FLOAT {
+			_, v := _float32ToBlr(float32(f))
+			return v, nil
+		}
+		_, v := _float64ToBlr(f)
+		return v, nil
+	}
+
+	blr, v, err := p.paramToBlr(transHandle, param, x)
+	if err != nil {
+		return nil, err
+	}
+	if blr[0] != arrayElementBlr[x.sqltype] {
+		return nil, fmt.Errorf("Can not convert %T to an array element of %s", param, x.typename())
+	}
+	return v, nil
+}
+
+// getArray reads the array of the ARRAY column x as nested slices.
+func (stmt *firebirdsqlStmt) getArray(x *xSQLVAR, id []byte) (interface{}, error) {
+	desc, err := stmt.arrayDesc(x)
+	if err != nil {
+		return nil, err
+	}
+	raws, err := stmt.wp.getSlice(stmt.tx.transHandle, id, desc)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		v, err := desc.element.value(raw, stmt.wp.timezone, "NONE")
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := v.([]byte); ok && desc.element.sqltype == SQL_TYPE_VARYING && desc.element.sqlsubtype != 1 {
+			v = string(b)
+		}
+		elements[i] = v
+	}
+	return desc.toSlice(elements)
+}
+
+// putArrays stores the slices bound to ARRAY parameters with op_put_slice,
+// and replaces them with the ids of the arrays.
+func (stmt *firebirdsqlStmt) putArrays(args []driver.Value) ([]driver.Value, error) {
+	var converted []driver.Value
+	for i := range stmt.paramXsqlda {
+		x := &stmt.paramXsqlda[i]
+		if x.sqltype != SQL_TYPE_ARRAY || i >= len(args) || !isArrayValue(args[i]) {
+			continue
+		}
+		desc, err := stmt.arrayDesc(x)
+		if err != nil {
+			return nil, err
+		}
+		elements, err := desc.flatten(reflect.ValueOf(args[i]), 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		for _, e := range elements {
+			b, err := stmt.wp.arrayElementToBytes(stmt.tx.transHandle, e, &desc.element)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+		}
+		id, err := stmt.wp.putSlice(stmt.tx.transHandle, desc, data)
+		if err != nil {
+			return nil, err
+		}
+		if converted == nil {
+			converted = append([]driver.Value(nil), args...)
+		}
+		converted[i] = arrayID(id)
+	}
+	if converted == nil {
+		return args, nil
+	}
+	return converted, nil
+}
@@ -0,0 +1,133 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArraySdl(t *testing.T) {
+	desc := &arrayDesc{
+		relname:   "T",
+		fieldname: "A",
+		element:   xSQLVAR{sqltype: SQL_TYPE_LONG},
+		lower:     []int{1},
+		upper:     []int{3},
+	}
+	assert.Equal(t, []byte{
+		isc_sdl_version1, isc_sdl_struct, 1, 8, 0,
+		isc_sdl_relation, 1, 'T', isc_sdl_field, 1, 'A',
+		isc_sdl_do1, 0, isc_sdl_tiny_integer, 3,
+		isc_sdl_element, 1, isc_sdl_scalar, 0, 1, isc_sdl_variable, 0,
+		isc_sdl_eoc,
+	}, desc.sdl())
+	assert.Equal(t, 12, desc.sliceLength())
+
+	desc = &arrayDesc{
+		relname:   "T",
+		fieldname: "B",
+		element:   xSQLVAR{sqltype: SQL_TYPE_VARYING, sqllen: 300, sqlsubtype: 4},
+		lower:     []int{0, 1},
+		upper:     []int{1, 1000},
+	}
+	assert.Equal(t, []byte{
+		isc_sdl_version1, isc_sdl_struct, 1, 38, 4, 0, 0x2c, 0x01,
+		isc_sdl_relation, 1, 'T', isc_sdl_field, 1, 'B',
+		isc_sdl_do2, 0, isc_sdl_tiny_integer, 0, isc_sdl_tiny_integer, 1,
+		isc_sdl_do1, 1, isc_sdl_short_integer, 0xe8, 0x03,
+		isc_sdl_element, 1, isc_sdl_scalar, 0, 2, isc_sdl_variable, 0, isc_sdl_variable, 1,
+		isc_sdl_eoc,
+	}, desc.sdl())
+	assert.Equal(t, 2*1000*302, desc.sliceLength())
+}
+
+func TestArraySlice(t *testing.T) {
+	desc := &arrayDesc{
+		fieldname: "A",
+		element:   xSQLVAR{sqltype: SQL_TYPE_SHORT},
+		lower:     []int{1, 1},
+		upper:     []int{2, 3},
+	}
+	elements := []interface{}{int16(1), int16(2), int16(3), int16(4), int16(5), int16(6)}
+	v, err := desc.toSlice(elements)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int16{{1, 2, 3}, {4, 5, 6}}, v)
+
+	flat, err := desc.flatten(reflect.ValueOf(v), 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, elements, flat)
+
+	flat, err = desc.flatten(reflect.ValueOf([]interface{}{[]int{1, 2, 3}, [3]int{4, 5, 6}}), 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6}, flat)
+
+	_, err = desc.flatten(reflect.ValueOf([][]int{{1, 2, 3}}), 0, nil)
+	assert.EqualError(t, err, "Array A needs 2 elements in dimension 1, got 1")
+	_, err = desc.flatten(reflect.ValueOf([]int{1, 2}), 0, nil)
+	assert.Error(t, err)
+
+	_, err = desc.toSlice(elements[:5])
+	assert.Error(t, err)
+
+	assert.True(t, isArrayValue([]int{1}))
+	assert.True(t, isArrayValue([][]byte{}))
+	assert.True(t, isArrayValue([2]string{}))
+	assert.False(t, isArrayValue([]byte{}))
+	assert.False(t, isArrayValue("a"))
+	assert.False(t, isArrayValue(nil))
+}
+
+func TestArrayElementToBytes(t *testing.T) {
+	p := &wireProtocol{dialect: 3, charset: "UTF8"}
+	tests := []struct {
+		x     xSQLVAR
+		param interface{}
+		want  []byte
+	}{
+		{xSQLVAR{sqltype: SQL_TYPE_SHORT}, 258, []byte{0, 0, 1, 2}},
+		{xSQLVAR{sqltype: SQL_TYPE_LONG, sqlscale: -2}, decimal.RequireFromString("1.5"), []byte{0, 0, 0, 150}},
+		{xSQLVAR{sqltype: SQL_TYPE_INT64}, int64(-1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{xSQLVAR{sqltype: SQL_TYPE_FLOAT}, 1, []byte{0x3f, 0x80, 0, 0}},
+		{xSQLVAR{sqltype: SQL_TYPE_DOUBLE}, float32(1), []byte{0x3f, 0xf0, 0, 0, 0, 0, 0, 0}},
+		{xSQLVAR{sqltype: SQL_TYPE_BOOLEAN}, true, []byte{1, 0, 0, 0}},
+		{xSQLVAR{sqltype: SQL_TYPE_TEXT, sqllen: 5, sqlsubtype: 4}, "ab", []byte{'a', 'b', ' ', ' ', ' ', 0, 0, 0}},
+		{xSQLVAR{sqltype: SQL_TYPE_TEXT, sqllen: 3, sqlsubtype: 1}, []byte{1}, []byte{1, 0, 0, 0}},
+		{xSQLVAR{sqltype: SQL_TYPE_VARYING, sqllen: 10, sqlsubtype: 4}, "abcde", []byte{0, 0, 0, 5, 'a', 'b', 'c', 'd', 'e', 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		b, err := p.arrayElementToBytes(0, tt.param, &tt.x)
+		require.NoError(t, err, tt.param)
+		assert.Equal(t, tt.want, b, tt.param)
+	}
+
+	_, err := p.arrayElementToBytes(0, "abcdef", &xSQLVAR{sqltype: SQL_TYPE_VARYING, sqllen: 5, sqlsubtype: 4})
+	assert.Error(t, err)
+	_, err = p.arrayElementToBytes(0, "x", &xSQLVAR{sqltype: SQL_TYPE_DATE})
+	assert.Error(t, err)
+}
@@ -0,0 +1,440 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrBlobClosed is returned when a closed Blob or BlobWriter is used.
+var ErrBlobClosed = errors.New("Blob is closed")
+
+// ErrBlobWriterNotClosed is returned when a BlobWriter is passed as a parameter before it is closed.
+var ErrBlobWriterNotClosed = errors.New("BlobWriter must be closed before it is used as a parameter")
+
+const (
+	// op_batch_segments carries up to 65535 bytes of segments, each prefixed with its length
+	blobBatchSize = 2 * BLOB_SEGMENT_SIZE
+	// number of op_batch_segments sent before their responses are read
+	blobPipelineDepth = 8
+)
+
+type blobStreamingKey struct{}
+
+// WithBlobStreaming returns a context which overrides the blob_streaming option
+// for queries executed with it.
+func WithBlobStreaming(ctx context.Context, streaming bool) context.Context {
+	return context.WithValue(ctx, blobStreamingKey{}, streaming)
+}
+
+func blobStreaming(ctx context.Context, defaultValue bool) bool {
+	if v, ok := ctx.Value(blobStreamingKey{}).(bool); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// Blob reads a BLOB value lazily from the database.
+// It is returned for BLOB columns instead of []byte or string when blob streaming
+// is enabled, and it is valid until the transaction ends.
+// Seek needs a stream BLOB; the server refuses to seek in segmented BLOBs.
+type Blob struct {
+	wp          *wireProtocol
+	transHandle int32
+	blobId      []byte
+	blobHandle  int32
+	opened      bool
+	closed      bool
+	buf         []byte
+	pos         int64
+	eof         bool
+}
+
+var _ io.ReadSeekCloser = (*Blob)(nil)
+
+func newBlob(wp *wireProtocol, transHandle int32, blobId []byte) *Blob {
+	return &Blob{wp: wp, transHandle: transHandle, blobId: blobId}
+}
+
+func (b *Blob) open() error {
+	if b.closed {
+		return ErrBlobClosed
+	}
+	if b.opened {
+		return nil
+	}
+	suspendBuf := b.wp.suspendBuffer()
+	defer b.wp.resumeBuffer(suspendBuf)
+	if err := b.wp.opOpenBlob(b.blobId, b.transHandle); err != nil {
+		return err
+	}
+	blobHandle, _, _, err := b.wp.opResponse()
+	if err != nil {
+		return err
+	}
+	b.blobHandle = blobHandle
+	b.opened = true
+	return nil
+}
+
+// Read reads up to len(p) bytes of the BLOB, fetching the segments on demand.
+func (b *Blob) Read(p []byte) (int, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	for len(b.buf) == 0 {
+		if b.eof {
+			return 0, io.EOF
+		}
+		suspendBuf := b.wp.suspendBuffer()
+		err := b.wp.opGetSegment(b.blobHandle)
+		var status int32
+		var rbuf []byte
+		if err == nil {
+			status, _, rbuf, err = b.wp.opResponse()
+		}
+		b.wp.resumeBuffer(suspendBuf)
+		if err != nil {
+			return 0, err
+		}
+		b.buf = appendSegments(b.buf, rbuf)
+		b.eof = status == 2
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	b.pos += int64(n)
+	return n, nil
+}
+
+// Seek sets the offset for the next Read with op_seek_blob.
+func (b *Blob) Seek(offset int64, whence int) (int64, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	var mode int32
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		mode = 2
+	default:
+		return 0, fmt.Errorf("Invalid whence %d", whence)
+	}
+	if mode == 0 && offset < 0 {
+		return 0, fmt.Errorf("Negative position %d", offset)
+	}
+	if offset < math.MinInt32 || offset > math.MaxInt32 {
+		return 0, fmt.Errorf("Offset %d overflows the BLOB seek range", offset)
+	}
+
+	suspendBuf := b.wp.suspendBuffer()
+	defer b.wp.resumeBuffer(suspendBuf)
+	if err := b.wp.opSeekBlob(b.blobHandle, mode, int32(offset)); err != nil {
+		return 0, err
+	}
+	pos, _, _, err := b.wp.opResponse()
+	if err != nil {
+		return 0, err
+	}
+	b.buf = nil
+	b.eof = false
+	b.pos = int64(uint32(pos))
+	return b.pos, nil
+}
+
+// Size returns the total length of the BLOB in bytes.
+func (b *Blob) Size() (int64, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	suspendBuf := b.wp.suspendBuffer()
+	defer b.wp.resumeBuffer(suspendBuf)
+	if err := b.wp.opInfoBlob(b.blobHandle, []byte{isc_info_blob_total_length}); err != nil {
+		return 0, err
+	}
+	_, _, buf, err := b.wp.opResponse()
+	if err != nil {
+		return 0, err
+	}
+	return parseInfo(buf, isc_info_blob_total_length)
+}
+
+// Close closes the BLOB handle on the server.
+func (b *Blob) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.buf = nil
+	if !b.opened {
+		return nil
+	}
+	suspendBuf := b.wp.suspendBuffer()
+	defer b.wp.resumeBuffer(suspendBuf)
+	if err := b.wp.opCloseBlob(b.blobHandle); err != nil {
+		return err
+	}
+	if b.wp.acceptType == ptype_lazy_send {
+		b.wp.lazyResponseCount++
+		return nil
+	}
+	_, _, _, err := b.wp.opResponse()
+	return err
+}
+
+// BlobWriter creates a BLOB incrementally in a transaction.
+// After Close, pass it as a parameter to store the BLOB in a column.
+// Text is written as is, without character set conversion.
+//
+// A BlobWriter lives until it is closed or cancelled, before the end of its transaction.
+// Its methods take the connection like the methods of the *sql.Conn, so they are not
+// called in the Raw callback of the connection, nor by several goroutines at a time.
+type BlobWriter struct {
+	conn        *sql.Conn
+	wp          *wireProtocol
+	transHandle int32
+	blobHandle  int32
+	blobId      []byte
+	data        []byte
+	pending     int
+	closed      bool
+}
+
+var _ io.WriteCloser = (*BlobWriter)(nil)
+var _ io.ReaderFrom = (*BlobWriter)(nil)
+
+func newBlobWriter(wp *wireProtocol, transHandle int32, stream bool) (*BlobWriter, error) {
+	var bpb []byte
+	if stream {
+		bpb = []byte{isc_bpb_version1, isc_bpb_type, 1, isc_bpb_type_stream}
+	}
+	suspendBuf := wp.suspendBuffer()
+	defer wp.resumeBuffer(suspendBuf)
+	if err := wp.opCreateBlob2(transHandle, bpb); err != nil {
+		return nil, err
+	}
+	blobHandle, blobId, _, err := wp.opResponse()
+	if err != nil {
+		return nil, err
+	}
+	return &BlobWriter{wp: wp, transHandle: transHandle, blobHandle: blobHandle, blobId: blobId}, nil
+}
+
+// NewBlobWriter creates a BLOB in the current transaction of conn, the transaction
+// of BeginTx when one is active. A stream BLOB is created when stream is true,
+// a segmented BLOB otherwise.
+func NewBlobWriter(ctx context.Context, conn *sql.Conn, stream bool) (*BlobWriter, error) {
+	var w *BlobWriter
+	err := conn.Raw(func(driverConn any) (err error) {
+		fc, ok := driverConn.(*firebirdsqlConn)
+		if !ok {
+			return errors.New("Not a firebirdsql connection")
+		}
+		if fc.tx.needBegin {
+			if err = fc.tx.begin(); err != nil {
+				return err
+			}
+		}
+		w, err = newBlobWriter(fc.wp, fc.tx.transHandle, stream)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+// raw runs the wire operations of f holding the connection of w.
+func (w *BlobWriter) raw(f func() error) error {
+	if w.conn == nil {
+		return f()
+	}
+	return w.conn.Raw(func(any) error {
+		return f()
+	})
+}
+
+// write sends the data in full batches without waiting for all of their responses.
+func (w *BlobWriter) write(p []byte) error {
+	w.data = append(w.data, p...)
+	n := 0
+	for len(w.data)-n >= blobBatchSize {
+		if err := w.sendBatch(w.data[n : n+blobBatchSize]); err != nil {
+			return err
+		}
+		n += blobBatchSize
+	}
+	w.data = append(w.data[:0], w.data[n:]...)
+	return nil
+}
+
+func (w *BlobWriter) sendBatch(data []byte) error {
+	segments := make([]byte, 0, len(data)+4)
+	for len(data) > 0 {
+		ln := len(data)
+		if ln > BLOB_SEGMENT_SIZE {
+			ln = BLOB_SEGMENT_SIZE
+		}
+		segments = append(segments, byte(ln), byte(ln>>8))
+		segments = append(segments, data[:ln]...)
+		data = data[ln:]
+	}
+	if err := w.wp.opBatchSegments(w.blobHandle, segments); err != nil {
+		return err
+	}
+	w.pending++
+	if w.pending >= blobPipelineDepth {
+		return w.drain()
+	}
+	return nil
+}
+
+// drain reads the responses of the batches sent.
+func (w *BlobWriter) drain() error {
+	var err error
+	for ; w.pending > 0; w.pending-- {
+		if _, _, _, e := w.wp.opResponse(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Write writes p to the BLOB.
+func (w *BlobWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrBlobClosed
+	}
+	err := w.raw(func() error {
+		suspendBuf := w.wp.suspendBuffer()
+		defer w.wp.resumeBuffer(suspendBuf)
+		err := w.write(p)
+		if e := w.drain(); err == nil {
+			err = e
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReadFrom writes the data read from r until EOF to the BLOB.
+func (w *BlobWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.closed {
+		return 0, ErrBlobClosed
+	}
+	var total int64
+	err := w.raw(func() error {
+		suspendBuf := w.wp.suspendBuffer()
+		defer w.wp.resumeBuffer(suspendBuf)
+
+		var err error
+		buf := make([]byte, blobBatchSize)
+		for err == nil {
+			var n int
+			n, err = r.Read(buf)
+			if n > 0 {
+				total += int64(n)
+				if e := w.write(buf[:n]); e != nil {
+					err = e
+				}
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if e := w.drain(); err == nil {
+			err = e
+		}
+		return err
+	})
+	return total, err
+}
+
+// Close writes the buffered data and closes the BLOB.
+func (w *BlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	return w.raw(func() error {
+		suspendBuf := w.wp.suspendBuffer()
+		defer w.wp.resumeBuffer(suspendBuf)
+
+		var err error
+		if len(w.data) > 0 {
+			err = w.sendBatch(w.data)
+			w.data = nil
+		}
+		if e := w.drain(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return err
+		}
+		if err = w.wp.opCloseBlob(w.blobHandle); err != nil {
+			return err
+		}
+		if _, _, _, err = w.wp.opResponse(); err != nil {
+			return err
+		}
+		w.closed = true
+		return nil
+	})
+}
+
+// Cancel discards the BLOB, which is not stored. It is used after an error of Write,
+// ReadFrom or Close to release the BLOB in the transaction.
+func (w *BlobWriter) Cancel() error {
+	if w.closed {
+		return nil
+	}
+	return w.raw(w.cancel)
+}
+
+// cancel reads the responses of the batches sent and cancels the BLOB.
+func (w *BlobWriter) cancel() error {
+	suspendBuf := w.wp.suspendBuffer()
+	defer w.wp.resumeBuffer(suspendBuf)
+
+	w.data = nil
+	err := w.drain()
+	w.closed = true
+	w.blobId = nil
+	if e := w.wp.opCancelBlob(w.blobHandle); e != nil {
+		return e
+	}
+	if _, _, _, e := w.wp.opResponse(); err == nil {
+		err = e
+	}
+	return err
+}
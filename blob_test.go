@@ -0,0 +1,115 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendSegments(t *testing.T) {
+	buf := []byte{3, 0, 'a', 'b', 'c', 0, 0, 2, 0, 'd', 'e'}
+	assert.Equal(t, []byte("abcde"), appendSegments(nil, buf))
+	assert.Equal(t, []byte("xabcde"), appendSegments([]byte("x"), buf))
+}
+
+func TestParseInfo(t *testing.T) {
+	buf := []byte{
+		isc_info_blob_max_segment, 2, 0, 0x00, 0x40,
+		isc_info_blob_total_length, 4, 0, 0x00, 0x00, 0x01, 0x80,
+		isc_info_end,
+	}
+	v, err := parseInfo(buf, isc_info_blob_total_length)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0x80010000), v)
+
+	_, err = parseInfo(buf, isc_info_blob_type)
+	assert.Error(t, err)
+}
+
+func TestBlobStreamingContext(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, blobStreaming(ctx, false))
+	assert.True(t, blobStreaming(ctx, true))
+	assert.True(t, blobStreaming(WithBlobStreaming(ctx, true), false))
+	assert.False(t, blobStreaming(WithBlobStreaming(ctx, false), true))
+}
+
+func TestCheckNamedValueReader(t *testing.T) {
+	r := bytes.NewReader([]byte("abc"))
+	nv := &driver.NamedValue{Ordinal: 1, Value: r}
+	require.NoError(t, checkNamedValue(nv))
+	assert.Equal(t, r, nv.Value)
+
+	w := &BlobWriter{}
+	nv = &driver.NamedValue{Ordinal: 1, Value: w}
+	require.NoError(t, checkNamedValue(nv))
+	assert.Equal(t, w, nv.Value)
+
+	_, _, err := (&wireProtocol{}).valueToBlr(0, w)
+	assert.ErrorIs(t, err, ErrBlobWriterNotClosed)
+}
+
+func TestCreateBlobCancel(t *testing.T) {
+	client, server := net.Pipe()
+	conn, err := newWireChannel(client)
+	require.NoError(t, err)
+	p := &wireProtocol{conn: conn, protocolVersion: PROTOCOL_VERSION13}
+
+	response := func(handle int32, gdsCode int32) []byte {
+		b := bint32_to_bytes(op_response)
+		b = append(b, bint32_to_bytes(handle)...)
+		b = append(b, 0, 0, 0, 0, 0, 0, 0, 1) // object id
+		b = append(b, bint32_to_bytes(0)...)  // buffer length
+		if gdsCode != 0 {
+			b = append(b, bint32_to_bytes(isc_arg_gds)...)
+			b = append(b, bint32_to_bytes(gdsCode)...)
+		}
+		return append(b, bint32_to_bytes(isc_arg_end)...)
+	}
+	received := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, server)
+		received <- buf.Bytes()
+	}()
+	go func() {
+		server.Write(response(5, 0))         // op_create_blob2
+		server.Write(response(0, 335544347)) // op_batch_segments
+		server.Write(response(0, 0))         // op_cancel_blob
+	}()
+
+	_, err = p.createBlob([]byte("abc"), 1)
+	assert.Error(t, err)
+	client.Close()
+	sent := <-received
+	assert.Equal(t, append(bint32_to_bytes(op_cancel_blob), bint32_to_bytes(5)...), sent[len(sent)-8:])
+}
@@ -0,0 +1,192 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// charsetEncodings maps Firebird character set names to their encodings.
+// NONE, OCTETS, ASCII, UNICODE_FSS and UTF8 are passed through as they are.
+var charsetEncodings = map[string]encoding.Encoding{
+	"SJIS_0208":  japanese.ShiftJIS,
+	"EUCJ_0208":  japanese.EUCJP,
+	"DOS437":     charmap.CodePage437,
+	"DOS850":     charmap.CodePage850,
+	"DOS852":     charmap.CodePage852,
+	"DOS858":     charmap.CodePage858,
+	"DOS860":     charmap.CodePage860,
+	"DOS862":     charmap.CodePage862,
+	"DOS863":     charmap.CodePage863,
+	"DOS865":     charmap.CodePage865,
+	"DOS866":     charmap.CodePage866,
+	"ISO8859_1":  charmap.ISO8859_1,
+	"ISO8859_2":  charmap.ISO8859_2,
+	"ISO8859_3":  charmap.ISO8859_3,
+	"ISO8859_4":  charmap.ISO8859_4,
+	"ISO8859_5":  charmap.ISO8859_5,
+	"ISO8859_6":  charmap.ISO8859_6,
+	"ISO8859_7":  charmap.ISO8859_7,
+	"ISO8859_8":  charmap.ISO8859_8,
+	"ISO8859_9":  charmap.ISO8859_9,
+	"ISO8859_13": charmap.ISO8859_13,
+	"KSC_5601":   korean.EUCKR,
+	"WIN1250":    charmap.Windows1250,
+	"WIN1251":    charmap.Windows1251,
+	"WIN1252":    charmap.Windows1252,
+	"WIN1253":    charmap.Windows1253,
+	"WIN1254":    charmap.Windows1254,
+	"WIN1255":    charmap.Windows1255,
+	"WIN1256":    charmap.Windows1256,
+	"WIN1257":    charmap.Windows1257,
+	"WIN1258":    charmap.Windows1258,
+	"BIG_5":      traditionalchinese.Big5,
+	"GB_2312":    simplifiedchinese.GBK, // EUC-CN is a subset of GBK
+	"GBK":        simplifiedchinese.GBK,
+	"GB18030":    simplifiedchinese.GB18030,
+	"KOI8R":      charmap.KOI8R,
+	"KOI8U":      charmap.KOI8U,
+	"TIS620":     charmap.Windows874,
+}
+
+// charsetNameByID maps character set ids (the low byte of sqlsubtype of text columns) to names.
+var charsetNameByID = map[int]string{
+	0:  "NONE",
+	1:  "OCTETS",
+	2:  "ASCII",
+	3:  "UNICODE_FSS",
+	4:  "UTF8",
+	5:  "SJIS_0208",
+	6:  "EUCJ_0208",
+	9:  "DOS737",
+	10: "DOS437",
+	11: "DOS850",
+	12: "DOS865",
+	13: "DOS860",
+	14: "DOS863",
+	15: "DOS775",
+	16: "DOS858",
+	17: "DOS862",
+	18: "DOS864",
+	19: "NEXT",
+	21: "ISO8859_1",
+	22: "ISO8859_2",
+	23: "ISO8859_3",
+	34: "ISO8859_4",
+	35: "ISO8859_5",
+	36: "ISO8859_6",
+	37: "ISO8859_7",
+	38: "ISO8859_8",
+	39: "ISO8859_9",
+	40: "ISO8859_13",
+	44: "KSC_5601",
+	45: "DOS852",
+	46: "DOS857",
+	47: "DOS861",
+	48: "DOS866",
+	49: "DOS869",
+	50: "CYRL",
+	51: "WIN1250",
+	52: "WIN1251",
+	53: "WIN1252",
+	54: "WIN1253",
+	55: "WIN1254",
+	56: "BIG_5",
+	57: "GB_2312",
+	58: "WIN1255",
+	59: "WIN1256",
+	60: "WIN1257",
+	63: "KOI8R",
+	64: "KOI8U",
+	65: "WIN1258",
+	66: "TIS620",
+	67: "GBK",
+	68: "CP943C",
+	69: "GB18030",
+}
+
+// charsetMaxBytesPerChar returns the maximum bytes of a character of the character set id.
+func charsetMaxBytesPerChar(id int) int {
+	switch charsetNameByID[id] {
+	case "UTF8", "GB18030":
+		return 4
+	case "UNICODE_FSS", "EUCJ_0208":
+		return 3
+	case "SJIS_0208", "KSC_5601", "BIG_5", "GB_2312", "GBK", "CP943C":
+		return 2
+	}
+	return 1
+}
+
+// columnCharset returns the character set of the column values on the wire.
+// Values are transliterated to the connection character set, except for NONE connections.
+func (x *xSQLVAR) columnCharset(charset string) string {
+	if charset == "NONE" {
+		if name, ok := charsetNameByID[x.sqlsubtype&0xff]; ok {
+			return name
+		}
+	}
+	return charset
+}
+
+// blobCharset returns the character set of a text BLOB, which is declared in sqlscale.
+func (x *xSQLVAR) blobCharset(charset string) string {
+	if charset == "NONE" {
+		if name, ok := charsetNameByID[x.sqlscale&0xff]; ok {
+			return name
+		}
+	}
+	return charset
+}
+
+func decodeCharset(b []byte, charset string) ([]byte, error) {
+	enc, ok := charsetEncodings[charset]
+	if !ok {
+		return b, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("Can not decode %q from %s: %v", b, charset, err)
+	}
+	return decoded, nil
+}
+
+func encodeCharset(s string, charset string) ([]byte, error) {
+	enc, ok := charsetEncodings[charset]
+	if !ok {
+		return str_to_bytes(s), nil
+	}
+	encoded, err := enc.NewEncoder().Bytes(str_to_bytes(s))
+	if err != nil {
+		return nil, fmt.Errorf("Can not encode %q to %s: %v", s, charset, err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,141 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+func TestCharsetRoundTrip(t *testing.T) {
+	for name := range charsetEncodings {
+		b, err := encodeCharset("Firebird 123", name)
+		require.NoError(t, err, name)
+		decoded, err := decodeCharset(b, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, "Firebird 123", string(decoded), name)
+	}
+
+	var tests = []struct {
+		charset string
+		s       string
+		b       []byte
+	}{
+		{"WIN1251", "Привет", []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2}},
+		{"WIN1253", "Ω", []byte{0xd9}},
+		{"WIN1254", "ş", []byte{0xfe}},
+		{"ISO8859_4", "ā", []byte{0xe0}},
+		{"GB_2312", "中", []byte{0xd6, 0xd0}},
+		{"GB18030", "中", []byte{0xd6, 0xd0}},
+		{"UTF8", "中", []byte("中")},
+	}
+	for _, tt := range tests {
+		b, err := encodeCharset(tt.s, tt.charset)
+		require.NoError(t, err, tt.charset)
+		assert.Equal(t, tt.b, b, tt.charset)
+		decoded, err := decodeCharset(b, tt.charset)
+		require.NoError(t, err, tt.charset)
+		assert.Equal(t, tt.s, string(decoded), tt.charset)
+	}
+
+	_, err := encodeCharset("中", "WIN1251")
+	assert.Error(t, err)
+}
+
+func TestColumnCharset(t *testing.T) {
+	x := &xSQLVAR{sqltype: SQL_TYPE_VARYING, sqlsubtype: 52 | 3<<8} // WIN1251 with a collation
+	assert.Equal(t, "WIN1251", x.columnCharset("NONE"))
+	assert.Equal(t, "UTF8", x.columnCharset("UTF8"))
+
+	v, err := x.value([]byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2}, "", "NONE")
+	require.NoError(t, err)
+	assert.Equal(t, "Привет", string(v.([]byte)))
+
+	p := &wireProtocol{dialect: 3, charset: "NONE"}
+	_, b, err := p.paramToBlr(0, "Привет", x)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2, 0, 0}, b)
+
+	p.charset = "WIN1251"
+	_, b, err = p.valueToBlr(0, "Привет")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2, 0, 0}, b)
+
+	x = &xSQLVAR{sqltype: SQL_TYPE_BLOB, sqlsubtype: 1, sqlscale: 53} // WIN1252 text blob
+	assert.Equal(t, "WIN1252", x.blobCharset("NONE"))
+	assert.Equal(t, "UTF8", x.blobCharset("UTF8"))
+}
+
+func TestCharDecode(t *testing.T) {
+	// CHAR(4) CHARACTER SET UTF8 is padded to 16 bytes
+	x := &xSQLVAR{sqltype: SQL_TYPE_TEXT, sqlsubtype: 4, sqllen: 16}
+	raw := append([]byte("Пр"), bytes.Repeat([]byte{' '}, 12)...)
+	v, err := x.value(raw, "", "UTF8")
+	require.NoError(t, err)
+	assert.Equal(t, "Пр  ", v)
+
+	// CHAR(3) CHARACTER SET WIN1251
+	x = &xSQLVAR{sqltype: SQL_TYPE_TEXT, sqlsubtype: 52, sqllen: 3}
+	v, err = x.value([]byte{0xcf, 0xf0, ' '}, "", "WIN1251")
+	require.NoError(t, err)
+	assert.Equal(t, "Пр ", v)
+}
+
+// failingTransformer fails on any input.
+type failingTransformer struct{ transform.NopResetter }
+
+func (failingTransformer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	return 0, 0, errors.New("invalid byte")
+}
+
+type failingEncoding struct{}
+
+func (failingEncoding) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: failingTransformer{}}
+}
+
+func (failingEncoding) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: failingTransformer{}}
+}
+
+func TestDecodeError(t *testing.T) {
+	charsetEncodings["TEST_FAILING"] = failingEncoding{}
+	defer delete(charsetEncodings, "TEST_FAILING")
+
+	_, err := decodeCharset([]byte{0xff}, "TEST_FAILING")
+	assert.EqualError(t, err, `Can not decode "\xff" from TEST_FAILING: invalid byte`)
+
+	x := &xSQLVAR{sqltype: SQL_TYPE_VARYING}
+	_, err = x.value([]byte{0xff}, "", "TEST_FAILING")
+	assert.Error(t, err)
+	x = &xSQLVAR{sqltype: SQL_TYPE_TEXT, sqllen: 1}
+	_, err = x.value([]byte{0xff}, "", "TEST_FAILING")
+	assert.Error(t, err)
+}
@@ -27,6 +27,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"math/big"
+	"strconv"
 )
 
 type firebirdsqlConn struct {
@@ -34,26 +35,25 @@ type firebirdsqlConn struct {
 	tx                *firebirdsqlTx
 	dsn               *firebirdDsn
 	columnNameToLower bool
+	charTrim          bool
+	blobStreaming     bool
+	stmtCache         *stmtCache
 	isAutocommit      bool
+	serverAutocommit  bool
 	clientPublic      *big.Int
 	clientSecret      *big.Int
 	transactionSet    map[*firebirdsqlTx]struct{}
+	txOptions         TxOptions
 }
 
 // ============ driver.Conn implementation
 
-func (fc *firebirdsqlConn) begin(isolationLevel int) (driver.Tx, error) {
-	tx, err := newFirebirdsqlTx(fc, isolationLevel, false, true)
-	fc.tx = tx
-	return driver.Tx(tx), err
-}
-
 // Begin starts and returns a new transaction.
 //
 // Deprecated: Drivers should implement ConnBeginTx instead (or additionally).
 // -> is implemented in driver_go18.go with BeginTx()
 func (fc *firebirdsqlConn) Begin() (driver.Tx, error) {
-	return fc.begin(ISOLATION_LEVEL_READ_COMMITED)
+	return fc.BeginTx(context.Background(), driver.TxOptions{})
 }
 
 // Close invalidates and potentially stops any current
@@ -79,14 +79,18 @@ func (fc *firebirdsqlConn) Close() (err error) {
 }
 
 func (fc *firebirdsqlConn) prepare(ctx context.Context, query string) (driver.Stmt, error) {
-	if fc.tx.needBegin {
-		err := fc.tx.begin()
+	tx, err := fc.transaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tx.needBegin {
+		err := tx.begin()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return newFirebirdsqlStmt(fc, query)
+	return newFirebirdsqlStmt(fc, tx, query)
 }
 
 // Prepare returns a prepared statement, bound to this connection.
@@ -94,41 +98,150 @@ func (fc *firebirdsqlConn) Prepare(query string) (driver.Stmt, error) {
 	return fc.prepare(context.Background(), query)
 }
 
-// ============ driver.Tx implementation
-
-func (fc *firebirdsqlConn) exec(ctx context.Context, query string, args []driver.Value) (result driver.Result, err error) {
+// prepareCached returns the statement of query from the statement cache,
+// or prepares it and adds it to the cache.
+func (fc *firebirdsqlConn) prepareCached(ctx context.Context, query string) (*firebirdsqlStmt, error) {
+	if fc.stmtCache != nil {
+		if stmt := fc.stmtCache.get(query); stmt != nil {
+			stmt.inUse = true
+			return stmt, nil
+		}
+	}
 
-	stmt, err := fc.prepare(ctx, query)
+	s, err := fc.prepare(ctx, query)
 	if err != nil {
-		return
+		return nil, err
+	}
+	stmt := s.(*firebirdsqlStmt)
+	stmt.inUse = true
+	if fc.stmtCache != nil {
+		fc.stmtCache.put(query, stmt)
+	}
+	return stmt, nil
+}
+
+// releaseStmt returns the statement to the cache, or drops it if it is not cached.
+func (fc *firebirdsqlConn) releaseStmt(stmt *firebirdsqlStmt) error {
+	stmt.inUse = false
+	if stmt.cacheKey != "" {
+		return nil
+	}
+	return stmt.Close()
+}
+
+// implicitTx returns the options of the transaction begun for statements outside of
+// BeginTx, and whether the driver commits it after each statement.
+// With server_autocommit, the server commits each statement instead.
+func (fc *firebirdsqlConn) implicitTx() (TxOptions, bool) {
+	opts := fc.txOptions
+	if fc.serverAutocommit && fc.isAutocommit {
+		opts.AutoCommit = true
+		return opts, false
+	}
+	return opts, fc.isAutocommit
+}
+
+// retryable reports whether the statement failed on the cached statements, which are dropped.
+// The transaction of autocommit is rolled back to execute the statement again, so the
+// statement runs twice; this is documented with statement_cache_size.
+func (fc *firebirdsqlConn) retryable(ctx context.Context, err error) bool {
+	if err == nil || fc.stmtCache == nil || !fc.stmtCache.invalidatedBy(err) {
+		return false
+	}
+	if tx, _ := fc.transaction(ctx); tx != nil && tx.isAutocommit && !tx.needBegin {
+		tx.Rollback()
 	}
+	return true
+}
+
+// ============ driver.Tx implementation
+
+func (fc *firebirdsqlConn) exec(ctx context.Context, query string, namedargs []driver.NamedValue) (result driver.Result, err error) {
+	result, err = fc.execOnce(ctx, query, namedargs)
+	if fc.retryable(ctx, err) {
+		result, err = fc.execOnce(ctx, query, namedargs)
+	}
+	return
+}
+
+func (fc *firebirdsqlConn) execOnce(ctx context.Context, query string, namedargs []driver.NamedValue) (result driver.Result, err error) {
 
-	result, err = stmt.(*firebirdsqlStmt).exec(ctx, args)
+	stmt, err := fc.prepareCached(ctx, query)
 	if err != nil {
 		return
 	}
 
-	stmt.Close()
+	args, err := stmt.bindArgs(namedargs)
+	if err == nil {
+		result, err = stmt.exec(ctx, args)
+	}
+	fc.releaseStmt(stmt)
 
 	return
 }
 
 func (fc *firebirdsqlConn) Exec(query string, args []driver.Value) (result driver.Result, err error) {
-	return fc.exec(context.Background(), query, args)
+	return fc.exec(context.Background(), query, valuesToNamedValues(args))
 }
 
-func (fc *firebirdsqlConn) query(ctx context.Context, query string, args []driver.Value) (rows driver.Rows, err error) {
+func (fc *firebirdsqlConn) query(ctx context.Context, query string, namedargs []driver.NamedValue) (rows driver.Rows, err error) {
+	rows, err = fc.queryOnce(ctx, query, namedargs)
+	if fc.retryable(ctx, err) {
+		rows, err = fc.queryOnce(ctx, query, namedargs)
+	}
+	return
+}
+
+func (fc *firebirdsqlConn) queryOnce(ctx context.Context, query string, namedargs []driver.NamedValue) (rows driver.Rows, err error) {
 
-	stmt, err := fc.prepare(ctx, query)
+	stmt, err := fc.prepareCached(ctx, query)
 	if err != nil {
 		return
 	}
-	rows, err = stmt.(*firebirdsqlStmt).query(ctx, args)
+
+	args, err := stmt.bindArgs(namedargs)
+	if err != nil {
+		fc.releaseStmt(stmt)
+		return
+	}
+	rows, err = stmt.query(ctx, args)
+	if err != nil {
+		fc.releaseStmt(stmt)
+		return
+	}
+	rows.(*firebirdsqlRows).releaseStmt = true
 	return
 }
 
 func (fc *firebirdsqlConn) Query(query string, args []driver.Value) (rows driver.Rows, err error) {
-	return fc.query(context.Background(), query, args)
+	return fc.query(context.Background(), query, valuesToNamedValues(args))
+}
+
+// initConn returns the connection of the attachment of wp with the options of dsn.
+func initConn(wp *wireProtocol, dsn *firebirdDsn, clientPublic, clientSecret *big.Int) (*firebirdsqlConn, error) {
+	txOptions, err := dsn.txOptions()
+	if err != nil {
+		return nil, err
+	}
+	fc := new(firebirdsqlConn)
+	fc.transactionSet = make(map[*firebirdsqlTx]struct{})
+	fc.wp = wp
+	fc.dsn = dsn
+	fc.columnNameToLower = convertToBool(dsn.options["column_name_to_lower"], false)
+	fc.charTrim = convertToBool(dsn.options["char_trim"], false)
+	fc.blobStreaming = convertToBool(dsn.options["blob_streaming"], false)
+	if size, _ := strconv.Atoi(dsn.options["statement_cache_size"]); size > 0 {
+		fc.stmtCache = newStmtCache(size)
+	}
+	fc.txOptions = txOptions
+	fc.serverAutocommit = convertToBool(dsn.options["server_autocommit"], false)
+	fc.isAutocommit = true
+	txOpts, isAutocommit := fc.implicitTx()
+	fc.tx, err = newFirebirdsqlTx(fc, txOpts, isAutocommit, false)
+	fc.clientPublic = clientPublic
+	fc.clientSecret = clientSecret
+
+	return fc, err
 }
 
 func newFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
@@ -137,8 +250,7 @@ func newFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
 	if err != nil {
 		return
 	}
-
-	column_name_to_lower := convertToBool(dsn.options["column_name_to_lower"], false)
+	wp.dialect, _ = strconv.Atoi(dsn.options["dialect"])
 
 	clientPublic, clientSecret := getClientSeed()
 
@@ -162,26 +274,16 @@ func newFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
 		return
 	}
 
-	fc = new(firebirdsqlConn)
-	fc.transactionSet = make(map[*firebirdsqlTx]struct{})
-	fc.wp = wp
-	fc.dsn = dsn
-	fc.columnNameToLower = column_name_to_lower
-	fc.isAutocommit = true
-	fc.tx, err = newFirebirdsqlTx(fc, ISOLATION_LEVEL_READ_COMMITED, fc.isAutocommit, false)
-	fc.clientPublic = clientPublic
-	fc.clientSecret = clientSecret
-
-	return fc, err
+	return initConn(wp, dsn, clientPublic, clientSecret)
 }
 
-func createFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
+func createFirebirdsqlConn(dsn *firebirdDsn, opts CreateOptions) (fc *firebirdsqlConn, err error) {
 
 	wp, err := newWireProtocol(dsn.addr, dsn.options["timezone"], dsn.options["charset"])
 	if err != nil {
 		return
 	}
-	column_name_to_lower := convertToBool(dsn.options["column_name_to_lower"], false)
+	wp.dialect, _ = strconv.Atoi(dsn.options["dialect"])
 
 	clientPublic, clientSecret := getClientSeed()
 
@@ -195,7 +297,7 @@ func createFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
 		return
 	}
 
-	err = wp.opCreate(dsn.dbName, dsn.user, dsn.passwd, dsn.options["role"])
+	err = wp.opCreate(dsn.dbName, dsn.user, dsn.passwd, dsn.options["role"], opts)
 	if err != nil {
 		return
 	}
@@ -205,15 +307,5 @@ func createFirebirdsqlConn(dsn *firebirdDsn) (fc *firebirdsqlConn, err error) {
 		return
 	}
 
-	fc = new(firebirdsqlConn)
-	fc.transactionSet = make(map[*firebirdsqlTx]struct{})
-	fc.wp = wp
-	fc.dsn = dsn
-	fc.columnNameToLower = column_name_to_lower
-	fc.isAutocommit = true
-	fc.tx, err = newFirebirdsqlTx(fc, ISOLATION_LEVEL_READ_COMMITED, fc.isAutocommit, false)
-	fc.clientPublic = clientPublic
-	fc.clientSecret = clientSecret
-
-	return fc, err
+	return initConn(wp, dsn, clientPublic, clientSecret)
 }
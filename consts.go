@@ -53,6 +53,30 @@ const (
 	isc_info_length         = 126
 	isc_info_flag_end       = 127
 
+	isc_info_blob_num_segments = 4
+	isc_info_blob_max_segment  = 5
+	isc_info_blob_total_length = 6
+	isc_info_blob_type         = 7
+
+	isc_bpb_version1       = 1
+	isc_bpb_type           = 3
+	isc_bpb_type_segmented = 0
+	isc_bpb_type_stream    = 1
+
+	isc_sdl_version1      = 1
+	isc_sdl_eoc           = 255
+	isc_sdl_relation      = 2
+	isc_sdl_field         = 4
+	isc_sdl_struct        = 6
+	isc_sdl_variable      = 7
+	isc_sdl_scalar        = 8
+	isc_sdl_tiny_integer  = 9
+	isc_sdl_short_integer = 10
+	isc_sdl_long_integer  = 11
+	isc_sdl_do2           = 34
+	isc_sdl_do1           = 35
+	isc_sdl_element       = 36
+
 	isc_info_db_id                 = 4
 	isc_info_reads                 = 5
 	isc_info_writes                = 6
@@ -174,6 +198,10 @@ const (
 	isc_tpb_no_auto_undo     = 20
 	isc_tpb_lock_timeout     = 21
 
+	// FB4
+	isc_tpb_read_consistency   = 22
+	isc_tpb_at_snapshot_number = 23
+
 	// Service Parameter Block parameter
 	isc_spb_version1              = 1
 	isc_spb_current_version       = 2
@@ -200,6 +228,7 @@ const (
 	isc_dpb_version1              = 1
 	isc_dpb_page_size             = 4
 	isc_dpb_num_buffers           = 5
+	isc_dpb_sweep_interval        = 22
 	isc_dpb_force_write           = 24
 	isc_dpb_user_name             = 28
 	isc_dpb_password              = 29
@@ -315,6 +344,7 @@ const (
 	isc_info_tra_isolation          = 8
 	isc_info_tra_access             = 9
 	isc_info_tra_lock_timeout       = 10
+	fb_info_tra_snapshot_number     = 12
 
 	// SQL information items
 	isc_info_sql_select        = 4
@@ -385,20 +415,28 @@ const (
 	op_transaction        = 29
 	op_commit             = 30
 	op_rollback           = 31
+	op_reconnect          = 33
 	op_open_blob          = 35
 	op_get_segment        = 36
 	op_put_segment        = 37
+	op_cancel_blob        = 38
 	op_close_blob         = 39
 	op_info_database      = 40
 	op_info_transaction   = 42
+	op_info_blob          = 43
 	op_batch_segments     = 44
 	op_que_events         = 48
 	op_cancel_events      = 49
 	op_commit_retaining   = 50
+	op_prepare2           = 51
 	op_event              = 52
 	op_connect_request    = 53
 	op_aux_connect        = 53
 	op_create_blob2       = 57
+	op_get_slice          = 58
+	op_put_slice          = 59
+	op_slice              = 60
+	op_seek_blob          = 61
 	op_allocate_statement = 62
 	op_execute            = 63
 	op_execute_immediate  = 64
@@ -0,0 +1,151 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCollation is returned when a character set or collation of CreateOptions
+// is not a regular identifier.
+var ErrInvalidCollation = errors.New("Invalid character set or collation name")
+
+// isRegularIdentifier reports whether s is an unquoted SQL identifier, which names
+// a character set or a collation.
+func isRegularIdentifier(s string) bool {
+	if s == "" || len(s) > 63 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '_' || c == '$'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// CreateOptions holds the parameters used by CreateDatabase.
+type CreateOptions struct {
+	// PageSize is the database page size in bytes. 0 means 4096.
+	PageSize int
+	// Charset is the default character set of the database.
+	// Empty means the charset of the connection string.
+	Charset string
+	// Collation is the default collation of Charset. Empty keeps the charset default.
+	Collation string
+	// Dialect is the SQL dialect of the database.
+	// 0 means the dialect of the connection string, which is 3 by default.
+	Dialect int
+	// NoForcedWrites disables synchronous writes, which are enabled by default.
+	NoForcedWrites bool
+	// Overwrite replaces an existing database file.
+	Overwrite bool
+	// SweepInterval is the automatic sweep interval.
+	// 0 keeps the server default and a negative value disables automatic sweep.
+	SweepInterval int
+}
+
+// defaultCreateOptions returns the options used by the "firebirdsql_createdb" driver.
+func defaultCreateOptions() CreateOptions {
+	return CreateOptions{
+		PageSize:  4096,
+		Overwrite: true,
+	}
+}
+
+// CreateDatabase creates the database specified by dsn.
+// ctx is checked before the creation and before the default collation is set.
+func CreateDatabase(ctx context.Context, dsns string, opts CreateOptions) (err error) {
+	dsn, err := parseDSN(dsns)
+	if err != nil {
+		return err
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = 4096
+	}
+	charset := opts.Charset
+	if charset == "" {
+		charset = dsn.options["charset"]
+	}
+	if opts.Collation != "" && !(isRegularIdentifier(charset) && isRegularIdentifier(opts.Collation)) {
+		return ErrInvalidCollation
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	fc, err := createFirebirdsqlConn(dsn, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := fc.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	if opts.Collation != "" {
+		if err = ctx.Err(); err != nil {
+			// the database is created, without its default collation
+			return err
+		}
+		_, err = fc.exec(ctx, fmt.Sprintf("ALTER CHARACTER SET %s SET DEFAULT COLLATION %s", charset, opts.Collation), nil)
+	}
+	return err
+}
+
+// DropDatabase drops the database specified by dsn.
+func DropDatabase(ctx context.Context, dsns string) error {
+	dsn, err := parseDSN(dsns)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	fc, err := newFirebirdsqlConn(dsn)
+	if err != nil {
+		return err
+	}
+
+	err = fc.wp.opDropDatabase()
+	if err == nil {
+		_, _, _, err = fc.wp.opResponse()
+	}
+	if err != nil {
+		// the database is still attached
+		fc.Close()
+		return err
+	}
+	// the attachment ends with the database
+	fc.wp.conn.Close()
+	return nil
+}
@@ -0,0 +1,98 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDropDatabase(t *testing.T) {
+	ctx := context.Background()
+	testDsn := GetTestDSN("test_create_drop_")
+
+	err := CreateDatabase(ctx, testDsn, CreateOptions{
+		PageSize:  16384,
+		Charset:   "UTF8",
+		Collation: "UNICODE_CI",
+	})
+	require.NoError(t, err)
+
+	conn, err := sql.Open("firebirdsql", testDsn)
+	require.NoError(t, err)
+
+	var pageSize, dialect, forcedWrites int
+	err = conn.QueryRow("SELECT mon$page_size, mon$sql_dialect, mon$forced_writes FROM mon$database").Scan(&pageSize, &dialect, &forcedWrites)
+	require.NoError(t, err)
+	require.Equal(t, 16384, pageSize)
+	require.Equal(t, 3, dialect)
+	require.Equal(t, 1, forcedWrites)
+
+	var n int
+	_, err = conn.Exec("CREATE TABLE test_ci (s VARCHAR(10))")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_ci (s) VALUES ('abc')")
+	require.NoError(t, err)
+	err = conn.QueryRow("SELECT COUNT(*) FROM test_ci WHERE s = 'ABC'").Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	conn.Close()
+
+	// without Overwrite, an existing database is an error
+	err = CreateDatabase(ctx, testDsn, CreateOptions{})
+	require.Error(t, err)
+
+	err = DropDatabase(ctx, testDsn)
+	require.NoError(t, err)
+
+	conn, err = sql.Open("firebirdsql", testDsn)
+	require.NoError(t, err)
+	err = conn.Ping()
+	require.Error(t, err)
+	conn.Close()
+}
+
+func TestCreateDatabaseInvalidCollation(t *testing.T) {
+	assert.True(t, isRegularIdentifier("UNICODE_CI_AI"))
+	assert.True(t, isRegularIdentifier("utf8"))
+	assert.False(t, isRegularIdentifier(""))
+	assert.False(t, isRegularIdentifier("1UTF8"))
+	assert.False(t, isRegularIdentifier("UNICODE; DROP DATABASE"))
+	assert.False(t, isRegularIdentifier(`"UNICODE"`))
+
+	// rejected before connecting
+	err := CreateDatabase(context.Background(), "user:password@localhost/dbname", CreateOptions{
+		Charset:   "UTF8",
+		Collation: "UNICODE_CI COLLATION X",
+	})
+	assert.ErrorIs(t, err, ErrInvalidCollation)
+	err = CreateDatabase(context.Background(), "user:password@localhost/dbname?charset=UTF8--", CreateOptions{
+		Collation: "UNICODE_CI",
+	})
+	assert.ErrorIs(t, err, ErrInvalidCollation)
+}
@@ -24,9 +24,13 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"github.com/shopspring/decimal"
 	"math"
 	"math/big"
+	"strconv"
+	"strings"
 )
 
 func dpdBitToInt(dpd uint, mask uint) int {
@@ -118,32 +122,19 @@ func calcSignificand(prefix int64, dpdBits *big.Int, numBits int) *big.Int {
 	return v
 }
 
-func decimal128ToSignDigitsExponent(b []byte) (v *decimal.Decimal, sign int, digits *big.Int, exponent int32) {
+func decimal128ToSignDigitsExponent(b []byte) (special decFloatKind, sign int, digits *big.Int, exponent int32) {
 	// https://en.wikipedia.org/wiki/Decimal128_floating-point_format
 
 	var prefix int64
 	if (b[0] & 0x80) == 0x80 {
 		sign = 1
 	}
-	cf := (uint32(b[0]&0x7f) << 10) + uint32(b[1]<<2) + uint32(b[2]>>6)
+	cf := (uint32(b[0]&0x7f) << 10) + (uint32(b[1]) << 2) + uint32(b[2]>>6)
 	if (cf & 0x1F000) == 0x1F000 {
-		var d decimal.Decimal
-		if sign == 1 {
-			// Is there -NaN ?
-			d = decimal.NewFromFloat(math.NaN())
-		} else {
-			d = decimal.NewFromFloat(math.NaN())
-		}
-		v = &d
+		special = decFloatNaN
 		return
 	} else if (cf & 0x1F000) == 0x1E000 {
-		var d decimal.Decimal
-		if sign == 1 {
-			d = decimal.NewFromFloat(math.Inf(-1))
-		} else {
-			d = decimal.NewFromFloat(math.Inf(1))
-		}
-		v = &d
+		special = decFloatInf
 		return
 	} else if (cf & 0x18000) == 0x00000 {
 		exponent = int32(0x0000 + (cf & 0x00fff))
@@ -176,18 +167,23 @@ func decimal128ToSignDigitsExponent(b []byte) (v *decimal.Decimal, sign int, dig
 	return
 }
 
-func decimalFixedToDecimal(b []byte, scale int32) decimal.Decimal {
-	v, sign, digits, _ := decimal128ToSignDigitsExponent(b)
-	if v != nil {
-		return *v
+func newDecFloatFromSignDigits(special decFloatKind, sign int, digits *big.Int, exponent int32) DecFloat {
+	if special != decFloatFinite {
+		return DecFloat{kind: special, negative: sign != 0}
 	}
 	if sign != 0 {
-		digits.Mul(digits, big.NewInt(-1))
+		digits.Neg(digits)
 	}
-	return decimal.NewFromBigInt(digits, scale)
+	return DecFloat{decimal: decimal.NewFromBigInt(digits, exponent), negative: sign != 0}
 }
 
-func decimal64ToDecimal(b []byte) decimal.Decimal {
+func decimalFixedToDecimal(b []byte, scale int32) decimal.Decimal {
+	special, sign, digits, _ := decimal128ToSignDigitsExponent(b)
+	d, _ := newDecFloatFromSignDigits(special, sign, digits, scale).Decimal()
+	return d
+}
+
+func decimal64ToDecFloat(b []byte) DecFloat {
 	// https://en.wikipedia.org/wiki/Decimal64_floating-point_format
 	var prefix int64
 	var sign int
@@ -202,16 +198,9 @@ func decimal64ToDecimal(b []byte) decimal.Decimal {
 	dpdBits.And(dpdBits, mask)
 
 	if cf == 0x1f {
-		if sign == 1 {
-			// Is there -NaN ?
-			return decimal.NewFromFloat(math.NaN())
-		}
-		return decimal.NewFromFloat(math.NaN())
+		return newDecFloatFromSignDigits(decFloatNaN, sign, nil, 0)
 	} else if cf == 0x1e {
-		if sign == 1 {
-			return decimal.NewFromFloat(math.Inf(-1))
-		}
-		return decimal.NewFromFloat(math.Inf(1))
+		return newDecFloatFromSignDigits(decFloatInf, sign, nil, 0)
 	} else if (cf & 0x18) == 0x00 {
 		exponent = 0x000 + exponent
 		prefix = int64(cf & 0x07)
@@ -236,20 +225,281 @@ func decimal64ToDecimal(b []byte) decimal.Decimal {
 	digits := calcSignificand(prefix, dpdBits, 50)
 	exponent -= 398
 
-	if sign != 0 {
-		digits.Mul(digits, big.NewInt(-1))
+	return newDecFloatFromSignDigits(decFloatFinite, sign, digits, exponent)
+}
+
+func decimal128ToDecFloat(b []byte) DecFloat {
+	// https://en.wikipedia.org/wiki/Decimal128_floating-point_format
+	return newDecFloatFromSignDigits(decimal128ToSignDigitsExponent(b))
+}
+
+func intToDpd(n int64) uint {
+	// Convert int (0-999) to DPD encoded value. 10bit unsigned int
+	d2 := uint(n / 100)
+	d1 := uint(n / 10 % 10)
+	d0 := uint(n % 10)
+
+	switch {
+	case d2 < 8 && d1 < 8 && d0 < 8:
+		return d2<<7 | d1<<4 | d0
+	case d2 < 8 && d1 < 8: // d0 large
+		return d2<<7 | d1<<4 | 0x8 | d0&1
+	case d2 < 8 && d0 < 8: // d1 large
+		return d2<<7 | (d0>>1)<<5 | (d1&1)<<4 | 0xa | d0&1
+	case d1 < 8 && d0 < 8: // d2 large
+		return (d0>>1)<<8 | (d2&1)<<7 | d1<<4 | 0xc | d0&1
+	case d0 < 8: // d2, d1 large
+		return (d0>>1)<<8 | (d2&1)<<7 | (d1&1)<<4 | 0xe | d0&1
+	case d1 < 8: // d2, d0 large
+		return (d1>>1)<<8 | (d2&1)<<7 | 0x20 | (d1&1)<<4 | 0xe | d0&1
+	case d2 < 8: // d1, d0 large
+		return d2<<7 | 0x40 | (d1&1)<<4 | 0xe | d0&1
 	}
-	return decimal.NewFromBigInt(digits, exponent)
+	// all large
+	return (d2&1)<<7 | 0x60 | (d1&1)<<4 | 0xe | d0&1
 }
 
-func decimal128ToDecimal(b []byte) decimal.Decimal {
-	// https://en.wikipedia.org/wiki/Decimal64_floating-point_format
-	v, sign, digits, exponent := decimal128ToSignDigitsExponent(b)
-	if v != nil {
-		return *v
+// decFloatToBytes encodes d as IEEE 754 decimal64 (size 8) or decimal128 (size 16) with DPD significand.
+func decFloatToBytes(d DecFloat, size int) ([]byte, error) {
+	numDigits, bias, expBits := 16, 398, 8
+	if size == 16 {
+		numDigits, bias, expBits = 34, 6176, 12
 	}
-	if sign != 0 {
-		digits.Mul(digits, big.NewInt(-1))
+	totalBits := size * 8
+
+	v := new(big.Int)
+	combination := uint(0)
+	var sign uint
+	if d.negative {
+		sign = 1
+	}
+
+	switch d.kind {
+	case decFloatNaN:
+		combination = 0x1f
+	case decFloatInf:
+		combination = 0x1e
+	default:
+		coef := d.decimal.Coefficient()
+		exp := int(d.decimal.Exponent())
+		if coef.Sign() < 0 {
+			sign = 1
+			coef.Neg(coef)
+		} else if coef.Sign() > 0 {
+			sign = 0
+		}
+		bi10 := big.NewInt(10)
+		r := new(big.Int)
+		// drop trailing zeros which do not fit, or which push the exponent below the minimum
+		for coef.Sign() != 0 && (len(coef.String()) > numDigits || exp+bias < 0) {
+			q, m := new(big.Int).QuoRem(coef, bi10, r)
+			if m.Sign() != 0 {
+				break
+			}
+			coef = q
+			exp++
+		}
+		if len(coef.String()) > numDigits {
+			return nil, fmt.Errorf("Value %v has more than %d significant digits", d, numDigits)
+		}
+		maxExp := 3<<uint(expBits) - 1
+		if coef.Sign() == 0 {
+			if exp+bias < 0 {
+				exp = -bias
+			} else if exp+bias > maxExp {
+				exp = maxExp - bias
+			}
+		}
+		// pad zeros to an exponent which is too large (clamping)
+		for exp+bias > maxExp && len(coef.String()) < numDigits {
+			coef.Mul(coef, bi10)
+			exp--
+		}
+		if exp+bias > maxExp {
+			return nil, fmt.Errorf("Value %v overflows DECFLOAT(%d)", d, numDigits)
+		}
+		if exp+bias < 0 {
+			return nil, fmt.Errorf("Value %v underflows DECFLOAT(%d)", d, numDigits)
+		}
+
+		biasedExp := uint(exp + bias)
+		digits := fmt.Sprintf("%0*s", numDigits, coef.String())
+		leading := uint(digits[0] - '0')
+		expTop := biasedExp >> uint(expBits)
+		if leading < 8 {
+			combination = expTop<<3 | leading
+		} else {
+			combination = 0x18 | expTop<<1 | leading&1
+		}
+		v.SetUint64(uint64(biasedExp & (1<<uint(expBits) - 1)))
+		for i := 1; i < numDigits; i += 3 {
+			n, _ := strconv.Atoi(digits[i : i+3])
+			v.Lsh(v, 10)
+			v.Or(v, big.NewInt(int64(intToDpd(int64(n)))))
+		}
+	}
+
+	v.Or(v, new(big.Int).Lsh(big.NewInt(int64(combination)), uint(totalBits-6)))
+	v.Or(v, new(big.Int).Lsh(big.NewInt(int64(sign)), uint(totalBits-1)))
+	return v.FillBytes(make([]byte, size)), nil
+}
+
+type decFloatKind int
+
+const (
+	decFloatFinite decFloatKind = iota
+	decFloatNaN
+	decFloatInf
+)
+
+// DecFloat is a DECFLOAT(16) or DECFLOAT(34) value.
+// Unlike decimal.Decimal it can hold NaN, infinities and negative zero.
+//
+// DECFLOAT columns are read as decimal.Decimal, or as DecFloat when the value is
+// NaN or an infinity. Both can be scanned into a DecFloat.
+type DecFloat struct {
+	decimal  decimal.Decimal
+	kind     decFloatKind
+	negative bool
+}
+
+// NewDecFloat returns a finite DecFloat.
+func NewDecFloat(d decimal.Decimal) DecFloat {
+	return DecFloat{decimal: d, negative: d.Sign() < 0}
+}
+
+// DecFloatNaN returns a NaN DecFloat.
+func DecFloatNaN() DecFloat {
+	return DecFloat{kind: decFloatNaN}
+}
+
+// DecFloatInf returns positive infinity if sign >= 0, negative infinity if sign < 0.
+func DecFloatInf(sign int) DecFloat {
+	return DecFloat{kind: decFloatInf, negative: sign < 0}
+}
+
+// DecFloatNegativeZero returns a negative zero.
+func DecFloatNegativeZero() DecFloat {
+	return DecFloat{negative: true}
+}
+
+// ParseDecFloat parses a decimal string, "NaN", "Inf", "Infinity" or "-0".
+func ParseDecFloat(s string) (DecFloat, error) {
+	s = strings.TrimSpace(s)
+	body := strings.TrimLeft(s, "+-")
+	negative := strings.HasPrefix(s, "-")
+	switch strings.ToUpper(body) {
+	case "NAN", "SNAN":
+		return DecFloat{kind: decFloatNaN, negative: negative}, nil
+	case "INF", "INFINITY":
+		return DecFloat{kind: decFloatInf, negative: negative}, nil
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return DecFloat{}, err
+	}
+	return DecFloat{decimal: d, negative: negative}, nil
+}
+
+// IsNaN reports whether d is NaN.
+func (d DecFloat) IsNaN() bool {
+	return d.kind == decFloatNaN
+}
+
+// IsInf reports whether d is an infinity, according to sign like math.IsInf.
+func (d DecFloat) IsInf(sign int) bool {
+	return d.kind == decFloatInf && (sign == 0 || (sign > 0) != d.negative)
+}
+
+// Signbit reports whether d is negative or negative zero.
+func (d DecFloat) Signbit() bool {
+	return d.negative
+}
+
+// Decimal returns d as decimal.Decimal. ok is false for NaN and infinities.
+func (d DecFloat) Decimal() (v decimal.Decimal, ok bool) {
+	return d.decimal, d.kind == decFloatFinite
+}
+
+func (d DecFloat) String() string {
+	switch d.kind {
+	case decFloatNaN:
+		return "NaN"
+	case decFloatInf:
+		if d.negative {
+			return "-Infinity"
+		}
+		return "Infinity"
+	}
+	if d.negative && d.decimal.Sign() == 0 {
+		return "-0"
+	}
+	return d.decimal.String()
+}
+
+// driverValue returns decimal.Decimal for finite values, otherwise d itself.
+// Negative zero is d itself, as decimal.Decimal has no signed zero.
+func (d DecFloat) driverValue() interface{} {
+	if d.kind == decFloatFinite && !(d.negative && d.decimal.IsZero()) {
+		return d.decimal
+	}
+	return d
+}
+
+// Value implements the driver.Valuer interface.
+func (d DecFloat) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (d *DecFloat) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case DecFloat:
+		*d = v
+	case decimal.Decimal:
+		*d = NewDecFloat(v)
+	case float64:
+		*d = newDecFloatFromFloat(v)
+	case int64:
+		*d = NewDecFloat(decimal.NewFromInt(v))
+	case string:
+		*d, err = ParseDecFloat(v)
+	case []byte:
+		*d, err = ParseDecFloat(string(v))
+	default:
+		err = fmt.Errorf("Can not scan %T into DecFloat", src)
+	}
+	return
+}
+
+func newDecFloatFromFloat(f float64) DecFloat {
+	switch {
+	case math.IsNaN(f):
+		return DecFloatNaN()
+	case math.IsInf(f, 0):
+		return DecFloatInf(int(math.Copysign(1, f)))
+	}
+	return DecFloat{decimal: decimal.NewFromFloat(f), negative: math.Signbit(f)}
+}
+
+// paramToDecFloat converts a parameter for a DECFLOAT column.
+func paramToDecFloat(param driver.Value) (DecFloat, bool) {
+	switch f := param.(type) {
+	case DecFloat:
+		return f, true
+	case float64:
+		return newDecFloatFromFloat(f), true
+	case float32:
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return newDecFloatFromFloat(float64(f)), true
+		}
+		return DecFloat{decimal: decimal.NewFromFloat32(f), negative: math.Signbit(float64(f))}, true
+	case string:
+		d, err := ParseDecFloat(f)
+		return d, err == nil
+	}
+	if d, ok := paramToDecimal(param); ok {
+		return NewDecFloat(d), true
 	}
-	return decimal.NewFromBigInt(digits, exponent)
+	return DecFloat{}, false
 }
@@ -0,0 +1,104 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDpd(t *testing.T) {
+	seen := make(map[uint]bool)
+	for i := int64(0); i < 1000; i++ {
+		dpd := intToDpd(i)
+		assert.Less(t, dpd, uint(1024))
+		assert.False(t, seen[dpd], "duplicated DPD %x for %d", dpd, i)
+		seen[dpd] = true
+		assert.Equal(t, i, dpdToInt(dpd))
+	}
+}
+
+func TestDecFloatEncode(t *testing.T) {
+	b, err := decFloatToBytes(NewDecFloat(decimal.NewFromInt(1)), 8)
+	require.NoError(t, err)
+	assert.Equal(t, "2238000000000001", hex.EncodeToString(b))
+
+	b, err = decFloatToBytes(NewDecFloat(decimal.NewFromInt(1)), 16)
+	require.NoError(t, err)
+	assert.Equal(t, "22080000000000000000000000000001", hex.EncodeToString(b))
+
+	for _, s := range []string{
+		"0", "-0", "1", "-1", "0.1", "123.456", "-9999999999999999", "9.999999999999999E+384", "1E-398",
+		"NaN", "-NaN", "Infinity", "-Infinity",
+	} {
+		d, err := ParseDecFloat(s)
+		require.NoError(t, err)
+
+		b, err := decFloatToBytes(d, 8)
+		require.NoError(t, err, s)
+		d64 := decimal64ToDecFloat(b)
+		assert.Equal(t, d.String(), d64.String(), s)
+		assert.Equal(t, d.Signbit(), d64.Signbit(), s)
+
+		b, err = decFloatToBytes(d, 16)
+		require.NoError(t, err, s)
+		d128 := decimal128ToDecFloat(b)
+		assert.Equal(t, d.String(), d128.String(), s)
+		assert.Equal(t, d.Signbit(), d128.Signbit(), s)
+	}
+
+	d, _ := ParseDecFloat("1234567890123456789012345678901234")
+	b, err = decFloatToBytes(d, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890123456789012345678901234", decimal128ToDecFloat(b).String())
+	_, err = decFloatToBytes(d, 8)
+	assert.EqualError(t, err, "Value 1234567890123456789012345678901234 has more than 16 significant digits")
+
+	d, _ = ParseDecFloat("1E+400")
+	_, err = decFloatToBytes(d, 8)
+	assert.ErrorContains(t, err, "overflows DECFLOAT(16)")
+}
+
+func TestDecFloatScan(t *testing.T) {
+	var d DecFloat
+	require.NoError(t, d.Scan(DecFloatInf(-1)))
+	assert.True(t, d.IsInf(-1))
+	assert.False(t, d.IsInf(1))
+	require.NoError(t, d.Scan(decimal.RequireFromString("1.5")))
+	v, ok := d.Decimal()
+	assert.True(t, ok)
+	assert.Equal(t, "1.5", v.String())
+	require.NoError(t, d.Scan(DecFloatNegativeZero().driverValue()))
+	assert.True(t, d.Signbit())
+	assert.Equal(t, "-0", d.String())
+	assert.IsType(t, decimal.Decimal{}, NewDecFloat(decimal.Zero).driverValue())
+	require.NoError(t, d.Scan("NaN"))
+	assert.True(t, d.IsNaN())
+	_, ok = d.Decimal()
+	assert.False(t, ok)
+}
@@ -46,7 +46,7 @@ func (d *firebirdsqlCreateDbDriver) Open(dsns string) (driver.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return createFirebirdsqlConn(dsn)
+	return createFirebirdsqlConn(dsn, defaultCreateOptions())
 }
 
 func init() {
@@ -28,62 +28,71 @@ package firebirdsql
 
 import (
 	"context"
-	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"sort"
 )
 
 func (stmt *firebirdsqlStmt) ExecContext(ctx context.Context, namedargs []driver.NamedValue) (result driver.Result, err error) {
-	sort.SliceStable(namedargs, func(i, j int) bool {
-		return namedargs[i].Ordinal < namedargs[j].Ordinal
-	})
-	args := make([]driver.Value, len(namedargs))
-	for i, nv := range namedargs {
-		args[i] = nv.Value
+	args, err := stmt.bindArgs(namedargs)
+	if err != nil {
+		return nil, err
 	}
 
 	return stmt.exec(ctx, args)
 }
 
 func (stmt *firebirdsqlStmt) QueryContext(ctx context.Context, namedargs []driver.NamedValue) (rows driver.Rows, err error) {
-	args := make([]driver.Value, len(namedargs))
-	for i, nv := range namedargs {
-		args[i] = nv.Value
+	args, err := stmt.bindArgs(namedargs)
+	if err != nil {
+		return nil, err
 	}
 
 	return stmt.query(ctx, args)
 }
 
+// CheckNamedValue implements driver.NamedValueChecker so that sql.Named arguments are accepted
+func (stmt *firebirdsqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
 func (fc *firebirdsqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	if opts.ReadOnly {
-		return fc.begin(ISOLATION_LEVEL_READ_COMMITED_RO)
+	if fc.tx != nil && fc.tx.opts.AutoCommit && !fc.tx.needBegin {
+		// the transaction of server_autocommit stays open across statements and
+		// checkouts from the pool until it is replaced here
+		if err := fc.tx.Commit(); err != nil {
+			return nil, err
+		}
 	}
-
-	switch (sql.IsolationLevel)(opts.Isolation) {
-	case sql.LevelDefault:
-		return fc.begin(ISOLATION_LEVEL_READ_COMMITED)
-	case sql.LevelReadCommitted:
-		return fc.begin(ISOLATION_LEVEL_READ_COMMITED)
-	case sql.LevelRepeatableRead:
-		return fc.begin(ISOLATION_LEVEL_REPEATABLE_READ)
-	case sql.LevelSerializable:
-		return fc.begin(ISOLATION_LEVEL_SERIALIZABLE)
-	default:
+	tx, err := newFirebirdsqlTx(fc, fc.txOptionsOf(ctx, opts), false, true)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("This isolation level is not supported.")
+	fc.tx = tx
+	return tx, nil
 }
 
+// PrepareContext prepares query. A statement prepared with a context made by
+// WithTransaction is executed in its transaction whatever the context of its calls.
 func (fc *firebirdsqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	return fc.prepare(ctx, query)
+	s, err := fc.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := ctx.Value(transactionKey{}).(*Transaction); ok && t != nil {
+		stmt := s.(*firebirdsqlStmt)
+		stmt.fixedTx = true
+		stmt.trans = t
+	}
+	return s, nil
 }
 
 func (fc *firebirdsqlConn) ExecContext(ctx context.Context, query string, namedargs []driver.NamedValue) (result driver.Result, err error) {
-	args := make([]driver.Value, len(namedargs))
-	for i, nv := range namedargs {
-		args[i] = nv.Value
-	}
-	return fc.exec(ctx, query, args)
+	return fc.exec(ctx, query, namedargs)
+}
+
+// CheckNamedValue implements driver.NamedValueChecker so that sql.Named arguments are accepted
+func (fc *firebirdsqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
 }
 
 // This file implements the optional pinger interface for the database/sql package
@@ -102,11 +111,7 @@ func (fc *firebirdsqlConn) Ping(ctx context.Context) (err error) {
 }
 
 func (fc *firebirdsqlConn) QueryContext(ctx context.Context, query string, namedargs []driver.NamedValue) (rows driver.Rows, err error) {
-	args := make([]driver.Value, len(namedargs))
-	for i, nv := range namedargs {
-		args[i] = nv.Value
-	}
-	return fc.query(ctx, query, args)
+	return fc.query(ctx, query, namedargs)
 }
 
 // ================== Implementation of the Connector interface ====================
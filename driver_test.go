@@ -24,13 +24,17 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"math"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -483,6 +487,45 @@ func TestDecFloat(t *testing.T) {
 	conn.Close()
 }
 
+func TestDecFloatParams(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_decfloat_params_"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	if get_firebird_major_version(conn) < 4 {
+		return
+	}
+
+	_, err = conn.Exec("CREATE TABLE test_decfloat_params (i integer, df16 DECFLOAT(16), df34 DECFLOAT(34))")
+	require.NoError(t, err)
+
+	values := []DecFloat{
+		NewDecFloat(decimal.RequireFromString("0.1234567890123456789012345678901234")),
+		DecFloatNaN(),
+		DecFloatInf(1),
+		DecFloatInf(-1),
+		DecFloatNegativeZero(),
+	}
+	for i, v := range values {
+		df16 := v
+		if d, ok := v.Decimal(); ok {
+			df16 = NewDecFloat(d.Round(16))
+		}
+		_, err = conn.Exec("insert into test_decfloat_params (i, df16, df34) values (?, ?, ?)", i, df16, v)
+		require.NoError(t, err)
+	}
+
+	rows, err := conn.Query("select df34 from test_decfloat_params order by i")
+	require.NoError(t, err)
+	for i := 0; rows.Next(); i++ {
+		var df34 DecFloat
+		require.NoError(t, rows.Scan(&df34))
+		assert.Equal(t, values[i].String(), df34.String())
+		assert.Equal(t, values[i].Signbit(), df34.Signbit())
+	}
+	rows.Close()
+}
+
 func TestTimeZone(t *testing.T) {
 	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_timezone_")+"?timezone=Asia/Tokyo")
 	if err != nil {
@@ -527,6 +570,32 @@ func TestTimeZone(t *testing.T) {
 	conn.Close()
 }
 
+func TestTimeZoneParams(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_timezone_params_")+"?timezone=Asia/Tokyo")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	if get_firebird_major_version(conn) < 4 {
+		return
+	}
+
+	_, err = conn.Exec("CREATE TABLE test_timezone_params (id INTEGER, a TIME WITH TIME ZONE, b TIMESTAMP WITH TIME ZONE)")
+	require.NoError(t, err)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	ts := time.Date(2023, 7, 1, 9, 30, 0, 0, newYork)
+	_, err = conn.Exec("insert into test_timezone_params (id, a, b) values (?, ?, ?)", 1, ts, ts)
+	require.NoError(t, err)
+
+	var a, b time.Time
+	err = conn.QueryRow("select a, b from test_timezone_params where id = 1").Scan(&a, &b)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(b), "%v", b)
+	assert.Equal(t, "America/New_York", b.Location().String())
+	assert.Equal(t, "09:30:00", a.Format("15:04:05"))
+}
+
 func TestInt128(t *testing.T) {
 	// https://github.com/nakagami/firebirdsql/issues/129
 	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_int128_"))
@@ -598,6 +667,31 @@ func TestNegativeInt128(t *testing.T) {
 	conn.Close()
 }
 
+func TestScaledInt128(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_scaled_int128_"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	if get_firebird_major_version(conn) < 4 {
+		return
+	}
+
+	_, err = conn.Exec("CREATE TABLE test_scaled_int128 (n NUMERIC(38,10))")
+	require.NoError(t, err)
+
+	amount := decimal.RequireFromString("-1234567890123456789012345678.0123456789")
+	_, err = conn.Exec("insert into test_scaled_int128 (n) values (?)", amount)
+	require.NoError(t, err)
+
+	var n decimal.Decimal
+	err = conn.QueryRow("SELECT n FROM test_scaled_int128 WHERE n = ?", amount).Scan(&n)
+	require.NoError(t, err)
+	assert.True(t, amount.Equal(n), "%v", n)
+
+	_, err = conn.Exec("insert into test_scaled_int128 (n) values (?)", decimal.RequireFromString("0.00000000001"))
+	assert.EqualError(t, err, "Value 0.00000000001 can not be stored with scale 10 without loss of precision")
+}
+
 func TestLegacyAuthWireCrypt(t *testing.T) {
 	test_dsn := GetTestDSN("test_legacy_auth_")
 	var n int
@@ -1185,3 +1279,467 @@ func TestReuseConnectionAfterTimeout(t *testing.T) {
 	_, err = conn.QueryContext(ctx, "select * from rdb$database")
 	require.NoError(t, err)
 }
+
+func TestDialect1(t *testing.T) {
+	testDsn := GetTestDSN("test_dialect1_")
+	err := CreateDatabase(context.Background(), testDsn, CreateOptions{Dialect: 1})
+	require.NoError(t, err)
+
+	conn, err := sql.Open("firebirdsql", testDsn+"?dialect=1")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_dialect1 (d DATE, n NUMERIC(15,2))")
+	require.NoError(t, err)
+
+	ts := time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local)
+	_, err = conn.Exec("INSERT INTO test_dialect1 (d, n) VALUES (?, ?)", ts, 1234.56)
+	require.NoError(t, err)
+
+	var d time.Time
+	var n decimal.Decimal
+	err = conn.QueryRow("SELECT d, n FROM test_dialect1").Scan(&d, &n)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(d), "DATE in dialect 1 keeps time part: %v", d)
+	assert.Equal(t, "1234.56", n.String())
+
+	rows, err := conn.Query("SELECT d, n FROM test_dialect1")
+	require.NoError(t, err)
+	ct, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, "TIMESTAMP", ct[0].DatabaseTypeName())
+	assert.Equal(t, reflect.TypeOf(decimal.Decimal{}), ct[1].ScanType())
+	rows.Close()
+}
+
+func TestNamedParams(t *testing.T) {
+	testDsn := GetTestDSN("test_named_params_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_named (a INTEGER, b VARCHAR(30), c INTEGER)")
+	require.NoError(t, err)
+
+	_, err = conn.Exec("INSERT INTO test_named (a, b, c) VALUES (:a, @b, :a)", sql.Named("b", "x:y"), sql.Named("a", 1))
+	require.NoError(t, err)
+
+	var a, c int
+	var b string
+	err = conn.QueryRow("SELECT a, b, c FROM test_named WHERE a = :a AND c = :A AND b <> ':b'", sql.Named("a", 1)).Scan(&a, &b, &c)
+	require.NoError(t, err)
+	assert.Equal(t, 1, a)
+	assert.Equal(t, "x:y", b)
+	assert.Equal(t, 1, c)
+
+	stmt, err := conn.Prepare("SELECT count(*) FROM test_named WHERE a = :a")
+	require.NoError(t, err)
+	var n int
+	err = stmt.QueryRow(sql.Named("a", 1)).Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stmt.Close()
+
+	_, err = conn.Exec("UPDATE test_named SET b = :b WHERE a = :a", sql.Named("a", 1))
+	assert.EqualError(t, err, "Missing named argument: b")
+	_, err = conn.Exec("UPDATE test_named SET b = :b WHERE a = :a", sql.Named("a", 1), sql.Named("b", "z"), sql.Named("c", 2))
+	assert.EqualError(t, err, "Unknown named argument: c")
+	_, err = conn.Exec("UPDATE test_named SET b = :b WHERE a = ?", sql.Named("b", "z"), 1)
+	assert.Equal(t, ErrMixedParameters, err)
+}
+
+func TestStmtParamTypes(t *testing.T) {
+	testDsn := GetTestDSN("test_stmt_param_types_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_param_types (a INTEGER NOT NULL, b VARCHAR(30), c NUMERIC(10,2))")
+	require.NoError(t, err)
+
+	stmt, err := conn.Prepare("INSERT INTO test_param_types (a, b, c) VALUES (?, ?, ?)")
+	require.NoError(t, err)
+	_, err = stmt.Exec(1, "x")
+	assert.EqualError(t, err, "sql: expected 3 arguments, got 2")
+	stmt.Close()
+
+	c, err := conn.Conn(context.Background())
+	require.NoError(t, err)
+	defer c.Close()
+	err = c.Raw(func(driverConn any) error {
+		paramTypes, err := driverConn.(RawConn).StmtParamTypes("UPDATE test_param_types SET b = :b, c = :c WHERE a = :a")
+		require.NoError(t, err)
+		require.Len(t, paramTypes, 3)
+		assert.Equal(t, "b", paramTypes[0].Name)
+		assert.Equal(t, "VARYING", paramTypes[0].DatabaseTypeName)
+		assert.Equal(t, int64(30), paramTypes[0].Length)
+		assert.Equal(t, int64(0), paramTypes[0].Precision)
+		assert.Equal(t, "c", paramTypes[1].Name)
+		assert.True(t, paramTypes[1].HasPrecisionScale)
+		assert.Equal(t, int64(10), paramTypes[1].Precision)
+		assert.Equal(t, int64(-2), paramTypes[1].Scale)
+		assert.Equal(t, "a", paramTypes[2].Name)
+		assert.Equal(t, "LONG", paramTypes[2].DatabaseTypeName)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestTypedParams(t *testing.T) {
+	testDsn := GetTestDSN("test_typed_params_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_typed (n NUMERIC(18,4), s SMALLINT, d DATE, t TIME)")
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 2, 29, 12, 34, 56, 0, time.Local)
+	_, err = conn.Exec("INSERT INTO test_typed (n, s, d, t) VALUES (?, ?, ?, ?)", 92233720368.5477, true, ts, ts)
+	require.NoError(t, err)
+
+	var n decimal.Decimal
+	var s int
+	var d, tm time.Time
+	err = conn.QueryRow("SELECT n, s, d, t FROM test_typed").Scan(&n, &s, &d, &tm)
+	require.NoError(t, err)
+	assert.Equal(t, "92233720368.5477", n.String())
+	assert.Equal(t, 1, s)
+	assert.Equal(t, "2024-02-29", d.Format("2006-01-02"))
+	assert.Equal(t, "12:34:56", tm.Format("15:04:05"))
+
+	_, err = conn.Exec("INSERT INTO test_typed (n) VALUES (?)", 0.12345)
+	assert.EqualError(t, err, "Value 0.12345 can not be stored with scale 4 without loss of precision")
+	_, err = conn.Exec("INSERT INTO test_typed (s) VALUES (?)", 70000)
+	assert.EqualError(t, err, "Value 70000 overflows SHORT")
+}
+
+func TestNativeParamTypes(t *testing.T) {
+	testDsn := GetTestDSN("test_native_params_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_native (n NUMERIC(18,4), f FLOAT, u BIGINT)")
+	require.NoError(t, err)
+
+	_, err = conn.Exec("INSERT INTO test_native (n, f, u) VALUES (?, ?, ?)", decimal.RequireFromString("12345678901234.5678"), float32(1.5), uint64(math.MaxInt64))
+	require.NoError(t, err)
+
+	var n decimal.Decimal
+	var f float32
+	var u int64
+	err = conn.QueryRow("SELECT n, f, u FROM test_native WHERE n = ?", decimal.RequireFromString("12345678901234.5678")).Scan(&n, &f, &u)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678901234.5678", n.String())
+	assert.Equal(t, float32(1.5), f)
+	assert.Equal(t, int64(math.MaxInt64), u)
+
+	_, err = conn.Exec("INSERT INTO test_native (u) VALUES (?)", uint64(math.MaxUint64))
+	assert.EqualError(t, err, "Value 18446744073709551615 overflows INT64")
+}
+
+func TestCharsetParams(t *testing.T) {
+	testDsn := GetTestDSN("test_charset_params_")
+	err := CreateDatabase(context.Background(), testDsn, CreateOptions{Charset: "WIN1251"})
+	require.NoError(t, err)
+
+	for i, charset := range []string{"WIN1251", "NONE"} {
+		conn, err := sql.Open("firebirdsql", testDsn+"?charset="+charset)
+		require.NoError(t, err)
+
+		if charset == "WIN1251" {
+			_, err = conn.Exec("CREATE TABLE test_charset (id INTEGER, s VARCHAR(30))")
+			require.NoError(t, err)
+		}
+		_, err = conn.Exec("INSERT INTO test_charset (id, s) VALUES (?, ?)", len(charset), "Привет")
+		require.NoError(t, err)
+
+		var s string
+		err = conn.QueryRow("SELECT s FROM test_charset WHERE id = ?", len(charset)).Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, "Привет", s, charset)
+
+		// the rows inserted with each connection charset match the parameter
+		var n int
+		err = conn.QueryRow("SELECT count(*) FROM test_charset WHERE s = ?", "Привет").Scan(&n)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, n, charset)
+		conn.Close()
+	}
+}
+
+func TestCharTrim(t *testing.T) {
+	testDsn := GetTestDSN("test_char_trim_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+
+	_, err = conn.Exec("CREATE TABLE test_char (s CHAR(10) CHARACTER SET UTF8)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_char (s) VALUES ('Привет')")
+	require.NoError(t, err)
+
+	var s string
+	err = conn.QueryRow("SELECT s FROM test_char").Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "Привет    ", s)
+	conn.Close()
+
+	conn, err = sql.Open("firebirdsql", testDsn+"?char_trim=true")
+	require.NoError(t, err)
+	err = conn.QueryRow("SELECT s FROM test_char").Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "Привет", s)
+	conn.Close()
+}
+
+func TestTextBlobCharset(t *testing.T) {
+	testDsn := GetTestDSN("test_text_blob_charset_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	_, err = conn.Exec("CREATE TABLE test_blob (id INTEGER, b BLOB SUB_TYPE TEXT CHARACTER SET WIN1252)")
+	require.NoError(t, err)
+	conn.Close()
+
+	for _, charset := range []string{"UTF8", "NONE"} {
+		conn, err = sql.Open("firebirdsql", testDsn+"?charset="+charset)
+		require.NoError(t, err)
+
+		_, err = conn.Exec("INSERT INTO test_blob (id, b) VALUES (?, ?)", len(charset), "Größe café")
+		require.NoError(t, err)
+
+		var s string
+		err = conn.QueryRow("SELECT b FROM test_blob WHERE id = ?", len(charset)).Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, "Größe café", s, charset)
+
+		err = conn.QueryRow("SELECT octet_length(b) FROM test_blob WHERE id = ?", len(charset)).Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, "10", s, charset)
+		conn.Close()
+	}
+}
+
+func TestBlobStreaming(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_blob_streaming_"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_blob (id INTEGER, b BLOB SUB_TYPE BINARY)")
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	_, err = conn.Exec("INSERT INTO test_blob (id, b) VALUES (1, ?)", data)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	var blob *Blob
+	err = tx.QueryRowContext(WithBlobStreaming(ctx, true), "SELECT b FROM test_blob WHERE id = 1").Scan(&blob)
+	require.NoError(t, err)
+	size, err := blob.Size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), size)
+	b, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+	require.NoError(t, blob.Close())
+	_, err = blob.Read(b)
+	assert.ErrorIs(t, err, ErrBlobClosed)
+
+	// eager loading is the default
+	b = nil
+	err = tx.QueryRow("SELECT b FROM test_blob WHERE id = 1").Scan(&b)
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+	require.NoError(t, tx.Commit())
+}
+
+func TestBlobWriter(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_blob_writer_"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE TABLE test_blob (id INTEGER, b BLOB SUB_TYPE BINARY)")
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+
+	// io.Reader parameter
+	_, err = conn.Exec("INSERT INTO test_blob (id, b) VALUES (1, ?)", bytes.NewReader(data))
+	require.NoError(t, err)
+	var b []byte
+	err = conn.QueryRow("SELECT b FROM test_blob WHERE id = 1").Scan(&b)
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+
+	// stream BLOB written incrementally in a transaction
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c.Close()
+	tx, err := c.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	w, err := NewBlobWriter(ctx, c, true)
+	require.NoError(t, err)
+	_, err = w.Write(data[:100])
+	require.NoError(t, err)
+	_, err = io.Copy(w, bytes.NewReader(data[100:]))
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO test_blob (id, b) VALUES (2, ?)", w)
+	assert.ErrorIs(t, err, ErrBlobWriterNotClosed)
+	require.NoError(t, w.Close())
+	_, err = tx.Exec("INSERT INTO test_blob (id, b) VALUES (2, ?)", w)
+	require.NoError(t, err)
+
+	// a cancelled BLOB is not stored
+	cancelled, err := NewBlobWriter(ctx, c, false)
+	require.NoError(t, err)
+	_, err = cancelled.Write(data[:100])
+	require.NoError(t, err)
+	require.NoError(t, cancelled.Cancel())
+	_, err = tx.Exec("INSERT INTO test_blob (id, b) VALUES (3, ?)", cancelled)
+	assert.ErrorIs(t, err, ErrBlobClosed)
+
+	var blob *Blob
+	err = tx.QueryRowContext(WithBlobStreaming(ctx, true), "SELECT b FROM test_blob WHERE id = 2").Scan(&blob)
+	require.NoError(t, err)
+	pos, err := blob.Seek(-10, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)-10), pos)
+	b, err = io.ReadAll(blob)
+	require.NoError(t, err)
+	assert.Equal(t, data[len(data)-10:], b)
+	require.NoError(t, blob.Close())
+	require.NoError(t, tx.Commit())
+}
+
+func TestArray(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_array_"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(`CREATE TABLE test_array (
+        id INTEGER,
+        i INTEGER[3],
+        m SMALLINT[0:1, 1:2],
+        n NUMERIC(10, 2)[2],
+        s VARCHAR(10)[2],
+        d DATE[1])`)
+	require.NoError(t, err)
+
+	d := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	_, err = conn.Exec("INSERT INTO test_array (id, i, m, n, s, d) VALUES (1, ?, ?, ?, ?, ?)",
+		[]int32{1, 2, 3},
+		[][]int16{{1, 2}, {3, 4}},
+		[]decimal.Decimal{decimal.RequireFromString("1.25"), decimal.RequireFromString("-3")},
+		[]string{"abc", "Привет"},
+		[]time.Time{d},
+	)
+	require.NoError(t, err)
+
+	var i []int32
+	var m [][]int16
+	var n []decimal.Decimal
+	var s []string
+	var ds []time.Time
+	err = conn.QueryRow("SELECT i, m, n, s, d FROM test_array WHERE id = 1").Scan(&i, &m, &n, &s, &ds)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2, 3}, i)
+	assert.Equal(t, [][]int16{{1, 2}, {3, 4}}, m)
+	assert.Equal(t, "1.25", n[0].String())
+	assert.Equal(t, "-3", n[1].String())
+	assert.Equal(t, []string{"abc", "Привет"}, s)
+	assert.True(t, d.Equal(ds[0]))
+
+	_, err = conn.Exec("UPDATE test_array SET i = ? WHERE id = 1", []int{4, 5, 6})
+	require.NoError(t, err)
+	err = conn.QueryRow("SELECT i FROM test_array WHERE id = 1").Scan(&i)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{4, 5, 6}, i)
+
+	_, err = conn.Exec("UPDATE test_array SET i = ? WHERE id = 1", []int{1, 2})
+	assert.Error(t, err)
+}
+
+func TestStmtReexecute(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_stmt_reexecute_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+
+	_, err = conn.Exec("CREATE TABLE test_stmt (id INTEGER)")
+	require.NoError(t, err)
+
+	ins, err := conn.Prepare("INSERT INTO test_stmt (id) VALUES (?)")
+	require.NoError(t, err)
+	defer ins.Close()
+	sel, err := conn.Prepare("SELECT count(*) FROM test_stmt WHERE id <= ?")
+	require.NoError(t, err)
+	defer sel.Close()
+
+	// the statements are prepared once and executed again after the autocommits
+	for i := 1; i <= 10; i++ {
+		_, err = ins.Exec(i)
+		require.NoError(t, err)
+
+		var n int
+		err = sel.QueryRow(i).Scan(&n)
+		require.NoError(t, err)
+		assert.Equal(t, i, n)
+	}
+
+	// a SELECT statement run by Exec closes its cursor
+	_, err = sel.Exec(1)
+	require.NoError(t, err)
+	_, err = sel.Exec(1)
+	require.NoError(t, err)
+
+	// rows closed before the end of the result
+	rows, err := conn.Query("SELECT id FROM test_stmt ORDER BY id")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	var n int
+	err = conn.QueryRow("SELECT count(*) FROM test_stmt").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+}
+
+func TestStmtCacheDDL(t *testing.T) {
+	testDsn := GetTestDSN("test_stmt_cache_")
+	conn, err := sql.Open("firebirdsql_createdb", testDsn)
+	require.NoError(t, err)
+	_, err = conn.Exec("CREATE TABLE test_cache (id INTEGER)")
+	require.NoError(t, err)
+	conn.Close()
+
+	conn, err = sql.Open("firebirdsql", testDsn+"?statement_cache_size=10")
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+
+	for i := 0; i < 3; i++ {
+		_, err = conn.Exec("INSERT INTO test_cache (id) VALUES (?)", i)
+		require.NoError(t, err)
+	}
+	var n int
+	err = conn.QueryRow("SELECT count(*) FROM test_cache").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	// the cached statements lock the table
+	_, err = conn.Exec("ALTER TABLE test_cache ADD s VARCHAR(10)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_cache (id, s) VALUES (?, ?)", 3, "a")
+	require.NoError(t, err)
+	err = conn.QueryRow("SELECT count(*) FROM test_cache").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	_, err = conn.Exec("INSERT INTO test_cache (id) VALUES (?)", "x")
+	var fbErr *FbError
+	require.ErrorAs(t, err, &fbErr)
+	assert.NotEmpty(t, fbErr.GDSCodes)
+}
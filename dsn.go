@@ -24,8 +24,10 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
+	"database/sql"
 	"errors"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -38,6 +40,10 @@ type firebirdDsn struct {
 }
 
 var ErrDsnUserUnknown = errors.New("User unknown")
+var ErrDsnInvalidDialect = errors.New("Invalid SQL dialect")
+var ErrDsnInvalidTxIsolation = errors.New("Invalid transaction isolation")
+var ErrDsnInvalidLockTimeout = errors.New("Invalid transaction lock timeout")
+var ErrDsnServerAutocommitIsolation = errors.New("server_autocommit needs a read committed tx_isolation")
 
 func newFirebirdDsn() *firebirdDsn {
 	return &firebirdDsn{options: make(map[string]string)}
@@ -77,10 +83,19 @@ func parseDSN(dsns string) (*firebirdDsn, error) {
 
 	var default_options = map[string]string{
 		"auth_plugin_name":     "Srp256",
+		"blob_streaming":       "false",
+		"char_trim":            "false",
 		"charset":              "UTF8",
 		"column_name_to_lower": "false",
+		"dialect":              "3",
 		"role":                 "",
+		"server_autocommit":    "false",
+		"statement_cache_size": "0",
 		"timezone":             "",
+		"tx_isolation":         "read_committed",
+		"tx_lock_timeout":      "0",
+		"tx_read_only":         "false",
+		"tx_wait":              "true",
 		"wire_crypt":           "true",
 	}
 
@@ -93,5 +108,56 @@ func parseDSN(dsns string) (*firebirdDsn, error) {
 		}
 	}
 
+	switch dsn.options["dialect"] {
+	case "1", "2", "3":
+	default:
+		return nil, ErrDsnInvalidDialect
+	}
+
+	if dsn.options["timezone"] != "" {
+		if _, err := loadTimezone(dsn.options["timezone"]); err != nil {
+			return nil, err
+		}
+	}
+
+	opts, err := dsn.txOptions()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = opts.tpb(); err != nil {
+		return nil, err
+	}
+	if convertToBool(dsn.options["server_autocommit"], false) && opts.Isolation != sql.LevelReadCommitted {
+		// a snapshot of server_autocommit would not see the data committed after it
+		return nil, ErrDsnServerAutocommitIsolation
+	}
+
 	return dsn, nil
 }
+
+// txOptions returns the options of the transactions begun without TxOptions.
+func (dsn *firebirdDsn) txOptions() (opts TxOptions, err error) {
+	switch dsn.options["tx_isolation"] {
+	case "read_committed":
+		opts.Isolation = sql.LevelReadCommitted
+	case "read_committed_no_rec_version":
+		opts.Isolation = sql.LevelReadCommitted
+		opts.ReadCommitted = NoRecordVersion
+	case "read_consistency":
+		opts.Isolation = sql.LevelReadCommitted
+		opts.ReadCommitted = ReadConsistency
+	case "snapshot":
+		opts.Isolation = sql.LevelSnapshot
+	case "serializable":
+		opts.Isolation = sql.LevelSerializable
+	default:
+		return opts, ErrDsnInvalidTxIsolation
+	}
+	opts.LockTimeout, err = strconv.Atoi(dsn.options["tx_lock_timeout"])
+	if err != nil || opts.LockTimeout < 0 {
+		return opts, ErrDsnInvalidLockTimeout
+	}
+	opts.ReadOnly = convertToBool(dsn.options["tx_read_only"], false)
+	opts.NoWait = !convertToBool(dsn.options["tx_wait"], true)
+	return opts, nil
+}
@@ -36,3 +36,28 @@ func NewErrOpResonse(opRCode int32) error { return &ErrOpResponse{opRCode: opRCo
 func (e *ErrOpResponse) Error() string    { return fmt.Sprintf("Error op_response:%d", e.opRCode) }
 
 var ErrOpSqlResponse = errors.New("Error op_sql_response")
+
+const (
+	isc_obsolete_metadata = 335544356
+	isc_obj_in_use        = 335544453
+)
+
+// FbError is an error returned by the Firebird server.
+type FbError struct {
+	// GDSCodes are the codes in the status vector, see errmsgs.go
+	GDSCodes []int
+	SQLCode  int
+	Message  string
+}
+
+func (e *FbError) Error() string { return e.Message }
+
+// HasGDSCode reports whether the status vector contains code.
+func (e *FbError) HasGDSCode(code int) bool {
+	for _, c := range e.GDSCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrMixedParameters       = errors.New("Named and positional parameters can not be mixed")
+	ErrNamedArgsNotSupported = errors.New("Named arguments are given but the statement has no named parameters")
+	ErrNamedArgsRequired     = errors.New("Statement has named parameters but positional arguments are given")
+)
+
+func isIdentStart(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || c == '_'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+// qStringEnd returns the closing delimiter of a Q-string literal q'<delim>...<delim>'
+func qStringEnd(c byte) byte {
+	switch c {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '<':
+		return '>'
+	}
+	return c
+}
+
+// skipLiteral returns the position just after the string literal, quoted identifier
+// or comment starting at query[i], or i when there is none.
+func skipLiteral(query string, i int) int {
+	n := len(query)
+	c := query[i]
+	switch {
+	case c == '\'' || c == '"':
+		for j := i + 1; j < n; j++ {
+			if query[j] == c {
+				if j+1 < n && query[j+1] == c { // escaped quote
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return n
+	case (c == 'q' || c == 'Q') && i+2 < n && query[i+1] == '\'' && (i == 0 || !isIdentChar(query[i-1])):
+		end := qStringEnd(query[i+2])
+		for j := i + 3; j+1 < n; j++ {
+			if query[j] == end && query[j+1] == '\'' {
+				return j + 2
+			}
+		}
+		return n
+	case c == '-' && i+1 < n && query[i+1] == '-':
+		if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+			return i + j + 1
+		}
+		return n
+	case c == '/' && i+1 < n && query[i+1] == '*':
+		if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+			return i + 2 + j + 2
+		}
+		return n
+	}
+	return i
+}
+
+// sqlKeywords returns the leading words of the statement, skipping comments.
+func sqlKeywords(query string, count int) []string {
+	var words []string
+	i := 0
+	for i < len(query) && len(words) < count {
+		if j := skipLiteral(query, i); j != i {
+			if query[i] == '\'' || query[i] == '"' {
+				break
+			}
+			i = j
+			continue
+		}
+		if isIdentStart(query[i]) {
+			j := i
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			words = append(words, strings.ToUpper(query[i:j]))
+			i = j
+			continue
+		}
+		if query[i] != ' ' && query[i] != '\t' && query[i] != '\r' && query[i] != '\n' {
+			break
+		}
+		i++
+	}
+	return words
+}
+
+// parseNamedParams rewrites :name and @name placeholders to ? and returns
+// the parameter names in order of appearance.
+// String literals, quoted identifiers, comments and the body of EXECUTE BLOCK
+// are left untouched. PSQL module definitions are never rewritten.
+func parseNamedParams(query string) (string, []string, error) {
+	words := sqlKeywords(query, 2)
+	if len(words) > 0 {
+		switch words[0] {
+		case "CREATE", "ALTER", "RECREATE":
+			return query, nil, nil
+		}
+	}
+	isExecuteBlock := len(words) == 2 && words[0] == "EXECUTE" && words[1] == "BLOCK"
+
+	var sb strings.Builder
+	var names []string
+	positional := 0
+	depth := 0
+	i := 0
+	for i < len(query) {
+		if j := skipLiteral(query, i); j != i {
+			sb.WriteString(query[i:j])
+			i = j
+			continue
+		}
+		c := query[i]
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '?':
+			positional++
+		case isIdentStart(c) && (i == 0 || !isIdentChar(query[i-1])):
+			j := i
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			if isExecuteBlock && depth == 0 && strings.EqualFold(query[i:j], "AS") {
+				// the rest is the PSQL body
+				sb.WriteString(query[i:])
+				i = len(query)
+				continue
+			}
+			sb.WriteString(query[i:j])
+			i = j
+			continue
+		case (c == ':' || c == '@') && i+1 < len(query) && isIdentStart(query[i+1]) && (i == 0 || !isIdentChar(query[i-1])):
+			j := i + 1
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			names = append(names, query[i+1:j])
+			sb.WriteByte('?')
+			i = j
+			continue
+		}
+		sb.WriteByte(c)
+		i++
+	}
+
+	if len(names) == 0 {
+		return query, nil, nil
+	}
+	if positional > 0 {
+		return "", nil, ErrMixedParameters
+	}
+	return sb.String(), names, nil
+}
+
+// bindNamedValues orders the arguments for a statement with the given parameter names.
+func bindNamedValues(paramNames []string, namedargs []driver.NamedValue) ([]driver.Value, error) {
+	sort.SliceStable(namedargs, func(i, j int) bool {
+		return namedargs[i].Ordinal < namedargs[j].Ordinal
+	})
+
+	if len(paramNames) == 0 {
+		args := make([]driver.Value, len(namedargs))
+		for i, nv := range namedargs {
+			if nv.Name != "" {
+				return nil, ErrNamedArgsNotSupported
+			}
+			args[i] = nv.Value
+		}
+		return args, nil
+	}
+
+	values := make(map[string]driver.Value, len(namedargs))
+	used := make(map[string]bool, len(namedargs))
+	for _, nv := range namedargs {
+		if nv.Name == "" {
+			return nil, ErrNamedArgsRequired
+		}
+		name := strings.ToUpper(nv.Name)
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("Duplicate named argument: %s", nv.Name)
+		}
+		values[name] = nv.Value
+	}
+
+	args := make([]driver.Value, len(paramNames))
+	for i, paramName := range paramNames {
+		name := strings.ToUpper(paramName)
+		v, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("Missing named argument: %s", paramName)
+		}
+		args[i] = v
+		used[name] = true
+	}
+	for _, nv := range namedargs {
+		if !used[strings.ToUpper(nv.Name)] {
+			return nil, fmt.Errorf("Unknown named argument: %s", nv.Name)
+		}
+	}
+	return args, nil
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	namedargs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		namedargs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return namedargs
+}
+
+func checkNamedValue(nv *driver.NamedValue) (err error) {
+	switch v := nv.Value.(type) {
+	case decimal.Decimal, DecFloat, float32, uint, uint16, uint32, uint64:
+		// encoded natively, not converted to string or int64
+		return nil
+	case *big.Int:
+		if v == nil {
+			nv.Value = nil
+		}
+		return nil
+	case *BlobWriter:
+		return nil
+	}
+	if _, ok := nv.Value.(driver.Valuer); !ok {
+		if _, ok := nv.Value.(io.Reader); ok {
+			// streamed into a BLOB
+			return nil
+		}
+		if isArrayValue(nv.Value) {
+			// stored as an ARRAY
+			return nil
+		}
+	}
+	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	return err
+}
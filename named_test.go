@@ -0,0 +1,104 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamedParams(t *testing.T) {
+	var tests = []struct {
+		query    string
+		expected string
+		names    []string
+	}{
+		{"select * from foo where a = ?", "select * from foo where a = ?", nil},
+		{"select * from foo where a = :a and b = @b or c = :a", "select * from foo where a = ? and b = ? or c = ?", []string{"a", "b", "a"}},
+		{"select ':a', \"@b\" from foo where c = :c", "select ':a', \"@b\" from foo where c = ?", []string{"c"}},
+		{"select 'it''s :a' from foo where c = :c", "select 'it''s :a' from foo where c = ?", []string{"c"}},
+		{"select q'{:a}' from foo where c = :c", "select q'{:a}' from foo where c = ?", []string{"c"}},
+		{"select a -- :a\nfrom foo /* :b */ where c = :c", "select a -- :a\nfrom foo /* :b */ where c = ?", []string{"c"}},
+		{"select '12:30:00' from foo", "select '12:30:00' from foo", nil},
+		{
+			"execute block (x integer = :x) returns (y integer) as begin y = :x; suspend; end",
+			"execute block (x integer = ?) returns (y integer) as begin y = :x; suspend; end",
+			[]string{"x"},
+		},
+		{
+			"execute block as declare a integer; begin select 1 from rdb$database into :a; end",
+			"execute block as declare a integer; begin select 1 from rdb$database into :a; end",
+			nil,
+		},
+		{
+			"create procedure p (x integer) returns (y integer) as begin y = :x; end",
+			"create procedure p (x integer) returns (y integer) as begin y = :x; end",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		query, names, err := parseNamedParams(tt.query)
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, query)
+		assert.Equal(t, tt.names, names)
+	}
+
+	_, _, err := parseNamedParams("select * from foo where a = :a and b = ?")
+	assert.Equal(t, ErrMixedParameters, err)
+}
+
+func TestBindNamedValues(t *testing.T) {
+	args, err := bindNamedValues([]string{"a", "B", "a"}, []driver.NamedValue{
+		{Name: "b", Ordinal: 2, Value: int64(2)},
+		{Name: "A", Ordinal: 1, Value: int64(1)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []driver.Value{int64(1), int64(2), int64(1)}, args)
+
+	args, err = bindNamedValues(nil, []driver.NamedValue{
+		{Ordinal: 2, Value: "b"},
+		{Ordinal: 1, Value: "a"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []driver.Value{"a", "b"}, args)
+
+	_, err = bindNamedValues([]string{"a", "b"}, []driver.NamedValue{{Name: "a", Ordinal: 1, Value: 1}})
+	assert.EqualError(t, err, "Missing named argument: b")
+
+	_, err = bindNamedValues([]string{"a"}, []driver.NamedValue{
+		{Name: "a", Ordinal: 1, Value: 1},
+		{Name: "c", Ordinal: 2, Value: 1},
+	})
+	assert.EqualError(t, err, "Unknown named argument: c")
+
+	_, err = bindNamedValues([]string{"a"}, []driver.NamedValue{{Ordinal: 1, Value: 1}})
+	assert.Equal(t, ErrNamedArgsRequired, err)
+
+	_, err = bindNamedValues(nil, []driver.NamedValue{{Name: "a", Ordinal: 1, Value: 1}})
+	assert.Equal(t, ErrNamedArgsNotSupported, err)
+}
@@ -0,0 +1,295 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// RawConn is the driver connection passed to sql.Conn.Raw.
+//
+//	err := conn.Raw(func(driverConn any) error {
+//	    paramTypes, err := driverConn.(firebirdsql.RawConn).StmtParamTypes(query)
+//	    ...
+//	})
+type RawConn interface {
+	// StmtParamTypes prepares query and returns the types of its input parameters.
+	StmtParamTypes(query string) ([]ParamType, error)
+	// LimboTransactions returns the transactions in limbo, which were prepared by the
+	// two-phase commit and neither committed nor rolled back, with their descriptions.
+	LimboTransactions(ctx context.Context) ([]LimboTransaction, error)
+	// CommitLimbo commits the transaction in limbo of id.
+	CommitLimbo(ctx context.Context, id int64) error
+	// RollbackLimbo rolls back the transaction in limbo of id.
+	RollbackLimbo(ctx context.Context, id int64) error
+}
+
+// ParamType describes an input parameter of a prepared statement.
+type ParamType struct {
+	// Name is the parameter name for :name and @name placeholders, empty for ?.
+	Name             string
+	DatabaseTypeName string
+	Length           int64
+	Nullable         bool
+	// Precision and Scale are valid when HasPrecisionScale is true. Precision is the
+	// declared precision of the column of the parameter, 0 when it is not a column.
+	Precision         int64
+	Scale             int64
+	HasPrecisionScale bool
+	ScanType          reflect.Type
+}
+
+var _ RawConn = (*firebirdsqlConn)(nil)
+
+const fieldPrecisionQuery = `SELECT F.RDB$FIELD_PRECISION
+FROM RDB$RELATION_FIELDS R
+JOIN RDB$FIELDS F ON F.RDB$FIELD_NAME = R.RDB$FIELD_SOURCE
+WHERE R.RDB$RELATION_NAME = ? AND R.RDB$FIELD_NAME = ?`
+
+// fieldPrecisions returns the declared precisions of the NUMERIC and DECIMAL parameters
+// of stmt, 0 for the parameters which are not a column.
+func (stmt *firebirdsqlStmt) fieldPrecisions() ([]int, error) {
+	precisions := make([]int, len(stmt.paramXsqlda))
+	var s *firebirdsqlStmt
+	for i, x := range stmt.paramXsqlda {
+		if !x.hasPrecisionScale() || x.relname == "" || x.fieldname == "" {
+			continue
+		}
+		if s == nil {
+			var err error
+			if s, err = newFirebirdsqlStmt(stmt.tx.fc, stmt.tx, fieldPrecisionQuery); err != nil {
+				return nil, err
+			}
+			defer s.free(2) // DSQL_drop
+			s.fixedTx = true
+		}
+		rows, err := s.query(context.Background(), []driver.Value{x.relname, x.fieldname})
+		if err != nil {
+			return nil, err
+		}
+		dest := make([]driver.Value, 1)
+		err = rows.Next(dest)
+		rows.Close()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		precisions[i] = metadataInt(dest[0])
+	}
+	return precisions, nil
+}
+
+func (fc *firebirdsqlConn) StmtParamTypes(query string) ([]ParamType, error) {
+	s, err := fc.prepare(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	stmt := s.(*firebirdsqlStmt)
+	precisions, err := stmt.fieldPrecisions()
+	if err != nil {
+		return nil, err
+	}
+
+	paramTypes := make([]ParamType, len(stmt.paramXsqlda))
+	for i, x := range stmt.paramXsqlda {
+		paramTypes[i] = ParamType{
+			DatabaseTypeName:  x.typename(),
+			Length:            int64(x.displayLength()),
+			Nullable:          x.null_ok,
+			Precision:         int64(precisions[i]),
+			Scale:             int64(x.sqlscale),
+			HasPrecisionScale: x.hasPrecisionScale(),
+			ScanType:          x.scantype(),
+		}
+		if i < len(stmt.paramNames) {
+			paramTypes[i].Name = stmt.paramNames[i]
+		}
+	}
+	return paramTypes, nil
+}
+
+// Transaction is a transaction of a connection, which can run beside other transactions
+// of the connection.
+//
+//	t, err := firebirdsql.BeginTransaction(ctx, conn, firebirdsql.TxOptions{ReadOnly: true})
+//	rows, err := t.QueryContext(ctx, query)
+//	// or through the connection
+//	rows, err := conn.QueryContext(firebirdsql.WithTransaction(ctx, t), query)
+//
+// Its methods take the connection like the methods of the *sql.Conn, so they are not
+// called in the Raw callback of the connection.
+type Transaction struct {
+	tx   *firebirdsqlTx
+	conn *sql.Conn
+	done bool
+}
+
+type transactionKey struct{}
+
+// WithTransaction returns a context with which statements of the connection of t
+// are prepared and executed in t.
+func WithTransaction(ctx context.Context, t *Transaction) context.Context {
+	return context.WithValue(ctx, transactionKey{}, t)
+}
+
+// ErrTransactionConnection is returned when a Transaction is used with another connection.
+var ErrTransactionConnection = errors.New("Transaction belongs to another connection")
+
+// transaction returns the transaction of ctx, or the current transaction of the connection.
+func (fc *firebirdsqlConn) transaction(ctx context.Context) (*firebirdsqlTx, error) {
+	t, ok := ctx.Value(transactionKey{}).(*Transaction)
+	if !ok || t == nil {
+		return fc.tx, nil
+	}
+	if t.done {
+		return nil, sql.ErrTxDone
+	}
+	if t.tx.fc != fc {
+		return nil, ErrTransactionConnection
+	}
+	return t.tx, nil
+}
+
+// BeginTransaction starts a transaction on conn, independent of the transaction of
+// database/sql.
+func BeginTransaction(ctx context.Context, conn *sql.Conn, opts TxOptions) (*Transaction, error) {
+	var tx *firebirdsqlTx
+	err := conn.Raw(func(driverConn any) (err error) {
+		fc, ok := driverConn.(*firebirdsqlConn)
+		if !ok {
+			return errors.New("Not a firebirdsql connection")
+		}
+		tx, err = newFirebirdsqlTx(fc, opts, false, true)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{tx: tx, conn: conn}, nil
+}
+
+// raw runs the wire operations of f holding the connection of t.
+func (t *Transaction) raw(f func() error) error {
+	if t.conn == nil {
+		return f()
+	}
+	return t.conn.Raw(func(any) error {
+		return f()
+	})
+}
+
+// PrepareContext prepares query in the transaction. The statement is executed in t
+// whatever the context of its calls.
+func (t *Transaction) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.conn.PrepareContext(WithTransaction(ctx, t), query)
+}
+
+// ExecContext executes query in the transaction.
+func (t *Transaction) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.conn.ExecContext(WithTransaction(ctx, t), query, args...)
+}
+
+// QueryContext executes query in the transaction.
+func (t *Transaction) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.conn.QueryContext(WithTransaction(ctx, t), query, args...)
+}
+
+// QueryRowContext executes query in the transaction.
+func (t *Transaction) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.conn.QueryRowContext(WithTransaction(ctx, t), query, args...)
+}
+
+// Commit commits the transaction.
+func (t *Transaction) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return t.raw(t.tx.Commit)
+}
+
+// SnapshotNumber returns the snapshot number of the transaction, Firebird 4 or later.
+// Transactions begun with TxOptions.AtSnapshotNumber of it see the same snapshot of the
+// database.
+func (t *Transaction) SnapshotNumber() (int64, error) {
+	if t.done {
+		return 0, sql.ErrTxDone
+	}
+	var n int64
+	err := t.raw(func() (err error) {
+		n, err = t.tx.snapshotNumber()
+		return
+	})
+	return n, err
+}
+
+// RowQueryer is a *sql.DB, *sql.Conn or *sql.Tx.
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SnapshotNumber returns the snapshot number of the transaction of q, Firebird 4 or later.
+//
+//	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSnapshot})
+//	n, err := firebirdsql.SnapshotNumber(ctx, tx)
+//	// on other connections
+//	tx, err := db.BeginTx(firebirdsql.WithTxOptions(ctx, firebirdsql.TxOptions{AtSnapshotNumber: n}), nil)
+func SnapshotNumber(ctx context.Context, q RowQueryer) (n int64, err error) {
+	err = q.QueryRowContext(ctx, "SELECT RDB$GET_CONTEXT('SYSTEM', 'SNAPSHOT_NUMBER') FROM RDB$DATABASE").Scan(&n)
+	return
+}
+
+// CommitRetaining commits the work of the transaction, and keeps the transaction and
+// its cursors open.
+func (t *Transaction) CommitRetaining() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	return t.raw(t.tx.commitRetaining)
+}
+
+// RollbackRetaining rolls back the work of the transaction, and keeps the transaction
+// and its cursors open.
+func (t *Transaction) RollbackRetaining() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	return t.raw(t.tx.rollbackRetaining)
+}
+
+// Rollback rolls back the transaction.
+func (t *Transaction) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return t.raw(t.tx.Rollback)
+}
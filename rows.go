@@ -24,7 +24,6 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
-	"bytes"
 	"container/list"
 	"context"
 	"database/sql/driver"
@@ -39,6 +38,7 @@ type firebirdsqlRows struct {
 	currentChunkRow *list.Element
 	moreData        bool
 	result          []driver.Value
+	releaseStmt     bool
 }
 
 func newFirebirdsqlRows(ctx context.Context, stmt *firebirdsqlStmt, result []driver.Value) *firebirdsqlRows {
@@ -63,9 +63,23 @@ func (rows *firebirdsqlRows) Columns() []string {
 	return columns
 }
 
-func (rows *firebirdsqlRows) Close() (er error) {
-	rows.stmt.Close()
+// Close closes the cursor, and releases the statement when it was prepared for the query.
+// The statement of a driver.Stmt is kept to be executed again.
+func (rows *firebirdsqlRows) Close() (err error) {
+	if rows.stmt.stmtType == isc_info_sql_stmt_select && (!rows.releaseStmt || rows.stmt.cacheKey != "") {
+		err = rows.stmt.free(1) // DSQL_close
+	}
+	if err == nil && rows.releaseStmt {
+		// a statement not cached is dropped with its cursor
+		err = rows.stmt.tx.fc.releaseStmt(rows.stmt)
+	}
+	if err != nil {
+		return
+	}
 
+	if rows.stmt.tx.isAutocommit {
+		err = rows.stmt.tx.Commit()
+	}
 	return
 }
 
@@ -78,7 +92,7 @@ func (rows *firebirdsqlRows) Next(dest []driver.Value) (err error) {
 	if rows.stmt.stmtType == isc_info_sql_stmt_exec_procedure {
 		if rows.result != nil {
 			for i, v := range rows.result {
-				dest[i] = v
+				dest[i] = rows.trimChar(i, v)
 			}
 			rows.result = nil
 		} else {
@@ -114,24 +128,45 @@ func (rows *firebirdsqlRows) Next(dest []driver.Value) (err error) {
 	}
 	row, _ := rows.currentChunkRow.Value.([]driver.Value)
 	for i, v := range row {
-		if rows.stmt.xsqlda[i].sqltype == SQL_TYPE_BLOB && v != nil {
+		if rows.stmt.xsqlda[i].sqltype == SQL_TYPE_BLOB && v != nil && blobStreaming(rows.ctx, rows.stmt.tx.fc.blobStreaming) {
+			dest[i] = newBlob(rows.stmt.wp, rows.stmt.tx.transHandle, v.([]byte))
+		} else if rows.stmt.xsqlda[i].sqltype == SQL_TYPE_ARRAY && v != nil {
+			dest[i], err = rows.stmt.getArray(&rows.stmt.xsqlda[i], v.([]byte))
+			if err != nil {
+				return
+			}
+		} else if rows.stmt.xsqlda[i].sqltype == SQL_TYPE_BLOB && v != nil {
 			blobId := v.([]byte)
 			var blob []byte
 			blob, err = rows.stmt.wp.getBlobSegments(blobId, rows.stmt.tx.transHandle)
-			if rows.stmt.xsqlda[i].sqlsubtype == 1 {
-				dest[i] = bytes.NewBuffer(blob).String()
+			if err != nil {
+				return
+			}
+			if x := rows.stmt.xsqlda[i]; x.sqlsubtype == 1 {
+				if blob, err = decodeCharset(blob, x.blobCharset(rows.stmt.wp.charset)); err != nil {
+					return
+				}
+				dest[i] = string(blob)
 			} else {
 				dest[i] = blob
 			}
 
 		} else {
-			dest[i] = v
+			dest[i] = rows.trimChar(i, v)
 		}
 	}
 
 	return
 }
 
+// trimChar strips the blank padding of CHAR values when char_trim is set
+func (rows *firebirdsqlRows) trimChar(i int, v driver.Value) driver.Value {
+	if s, ok := v.(string); ok && rows.stmt.tx.fc.charTrim && rows.stmt.xsqlda[i].sqltype == SQL_TYPE_TEXT {
+		return strings.TrimRight(s, " ")
+	}
+	return v
+}
+
 func (rows *firebirdsqlRows) ColumnTypeDatabaseTypeName(index int) string {
 	return rows.stmt.xsqlda[index].typename()
 }
@@ -0,0 +1,200 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// ErrInvalidSavepointName is returned for an empty savepoint name, or a name which is not
+// valid UTF-8 or has a NUL character. The length of a name is checked by the server: 31
+// bytes before Firebird 4, 63 characters since.
+var ErrInvalidSavepointName = errors.New("Invalid savepoint name")
+
+// ErrSavepointNotActive is returned when rolling back to or releasing a savepoint
+// which is not active in the transaction.
+var ErrSavepointNotActive = errors.New("Savepoint is not active")
+
+// quoteSavepoint validates name and returns it as a quoted identifier.
+func quoteSavepoint(name string) (string, error) {
+	if name == "" || !utf8.ValidString(name) || strings.ContainsRune(name, 0) {
+		return "", ErrInvalidSavepointName
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, nil
+}
+
+// savepointIndex returns the index of name in the active savepoints of tx, or -1.
+func (tx *firebirdsqlTx) savepointIndex(name string) int {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// execImmediate executes query without parameters in tx.
+func (tx *firebirdsqlTx) execImmediate(query string) error {
+	s, err := newFirebirdsqlStmt(tx.fc, tx, query)
+	if err != nil {
+		return err
+	}
+	defer s.free(2) // DSQL_drop
+	s.fixedTx = true
+	_, err = s.exec(context.Background(), nil)
+	return err
+}
+
+func (tx *firebirdsqlTx) savepoint(name string) error {
+	quoted, err := quoteSavepoint(name)
+	if err != nil {
+		return err
+	}
+	if err = tx.execImmediate("SAVEPOINT " + quoted); err != nil {
+		return err
+	}
+	// a savepoint of the same name is released by the server
+	if i := tx.savepointIndex(name); i >= 0 {
+		tx.savepoints = append(tx.savepoints[:i], tx.savepoints[i+1:]...)
+	}
+	tx.savepoints = append(tx.savepoints, name)
+	return nil
+}
+
+func (tx *firebirdsqlTx) rollbackTo(name string) error {
+	quoted, err := quoteSavepoint(name)
+	if err != nil {
+		return err
+	}
+	i := tx.savepointIndex(name)
+	if i < 0 {
+		return ErrSavepointNotActive
+	}
+	if err = tx.execImmediate("ROLLBACK TO SAVEPOINT " + quoted); err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+func (tx *firebirdsqlTx) release(name string) error {
+	quoted, err := quoteSavepoint(name)
+	if err != nil {
+		return err
+	}
+	i := tx.savepointIndex(name)
+	if i < 0 {
+		return ErrSavepointNotActive
+	}
+	if err = tx.execImmediate("RELEASE SAVEPOINT " + quoted); err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+// Savepoint sets a savepoint of name in the transaction. A savepoint of the same name
+// is replaced.
+func (t *Transaction) Savepoint(name string) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	return t.raw(func() error {
+		return t.tx.savepoint(name)
+	})
+}
+
+// RollbackTo undoes the work of the transaction after the savepoint of name, and
+// releases the savepoints set after it.
+func (t *Transaction) RollbackTo(name string) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	return t.raw(func() error {
+		return t.tx.rollbackTo(name)
+	})
+}
+
+// Release releases the savepoint of name and the savepoints set after it.
+func (t *Transaction) Release(name string) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	return t.raw(func() error {
+		return t.tx.release(name)
+	})
+}
+
+// savepointSeq numbers the savepoints of WithSavepoint, so that their names are unique
+// in any transaction.
+var savepointSeq uint64
+
+// WithSavepoint runs f in a savepoint of tx. The savepoint is released when f returns
+// nil, and the work of f is rolled back when f returns an error or panics, so that tx
+// can go on.
+//
+// Each savepoint has a unique name, so that WithSavepoint nests at any depth, and each f
+// only unwinds its own savepoint and the savepoints set in it.
+//
+//	err = firebirdsql.WithSavepoint(ctx, tx, func(ctx context.Context) error {
+//		_, err := tx.ExecContext(ctx, "INSERT INTO foo (a) VALUES (?)", 1)
+//		return err
+//	})
+func WithSavepoint(ctx context.Context, tx *sql.Tx, f func(ctx context.Context) error) (err error) {
+	quoted, err := quoteSavepoint(fmt.Sprintf("FIREBIRDSQL_SP_%d", atomic.AddUint64(&savepointSeq, 1)))
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT "+quoted); err != nil {
+		return err
+	}
+
+	unwind := func() error {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+quoted); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+quoted)
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			unwind()
+			panic(p)
+		}
+	}()
+	if err = f(ctx); err != nil {
+		if unwindErr := unwind(); unwindErr != nil {
+			return unwindErr
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+quoted)
+	return err
+}
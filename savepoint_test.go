@@ -0,0 +1,48 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteSavepoint(t *testing.T) {
+	quoted, err := quoteSavepoint("sp1")
+	assert.NoError(t, err)
+	assert.Equal(t, `"sp1"`, quoted)
+	quoted, err = quoteSavepoint(`a"; COMMIT; --`)
+	assert.NoError(t, err)
+	assert.Equal(t, `"a""; COMMIT; --"`, quoted)
+
+	for _, name := range []string{"", "a\x00b", "\xff"} {
+		_, err = quoteSavepoint(name)
+		assert.ErrorIs(t, err, ErrInvalidSavepointName, name)
+	}
+	// the length is checked by the server
+	_, err = quoteSavepoint(strings.Repeat("あ", 64))
+	assert.NoError(t, err)
+}
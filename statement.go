@@ -25,20 +25,36 @@ package firebirdsql
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
+	"strings"
 )
 
 type firebirdsqlStmt struct {
-	wp         *wireProtocol
-	stmtHandle int32
-	tx         *firebirdsqlTx
-	xsqlda     []xSQLVAR
-	blr        []byte
-	stmtType   int32
+	wp          *wireProtocol
+	stmtHandle  int32
+	tx          *firebirdsqlTx
+	xsqlda      []xSQLVAR
+	paramXsqlda []xSQLVAR
+	blr         []byte
+	stmtType    int32
+	paramNames  []string
+	arrayDescs  map[string]*arrayDesc
+	cacheKey    string // the query in the statement cache
+	inUse       bool
+	fixedTx     bool         // executed in tx, not in the transaction of the context
+	trans       *Transaction // the raw transaction of Transaction.PrepareContext
 }
 
+// Close drops the statement on the server.
 func (stmt *firebirdsqlStmt) Close() (err error) {
-	err = stmt.wp.opFreeStatement(stmt.stmtHandle, 2) // DSQL_drop
+	return stmt.free(2) // DSQL_drop
+}
+
+// free closes the cursor (DSQL_close) or drops the statement (DSQL_drop) on the server.
+func (stmt *firebirdsqlStmt) free(option int32) (err error) {
+	err = stmt.wp.opFreeStatement(stmt.stmtHandle, option)
 	if err != nil {
 		return err
 	}
@@ -48,15 +64,39 @@ func (stmt *firebirdsqlStmt) Close() (err error) {
 	} else {
 		_, _, _, err = stmt.wp.opResponse()
 	}
+	return
+}
 
-	if stmt.tx.isAutocommit {
-		stmt.tx.Commit()
+// begin executes the statement in the transaction of ctx or the current transaction
+// of the connection, which is started again after an autocommit.
+// A statement with fixedTx is executed in its own transaction.
+func (stmt *firebirdsqlStmt) begin(ctx context.Context) (err error) {
+	if stmt.trans != nil && stmt.trans.done {
+		return sql.ErrTxDone
 	}
-	return
+	if !stmt.fixedTx {
+		stmt.tx, err = stmt.tx.fc.transaction(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if stmt.tx.needBegin {
+		return stmt.tx.begin()
+	}
+	return nil
 }
 
+// NumInput returns the number of placeholders, or the number of distinct names
+// for a statement with named parameters.
 func (stmt *firebirdsqlStmt) NumInput() int {
-	return -1
+	if len(stmt.paramNames) > 0 {
+		names := make(map[string]bool, len(stmt.paramNames))
+		for _, name := range stmt.paramNames {
+			names[strings.ToUpper(name)] = true
+		}
+		return len(names)
+	}
+	return len(stmt.paramXsqlda)
 }
 
 func (stmt *firebirdsqlStmt) sendOpCancel(ctx context.Context, done chan struct{}) {
@@ -72,7 +112,15 @@ func (stmt *firebirdsqlStmt) sendOpCancel(ctx context.Context, done chan struct{
 }
 
 func (stmt *firebirdsqlStmt) exec(ctx context.Context, args []driver.Value) (result driver.Result, err error) {
-	err = stmt.wp.opExecute(stmt.stmtHandle, stmt.tx.transHandle, args)
+	err = stmt.begin(ctx)
+	if err != nil {
+		return
+	}
+	args, err = stmt.putArrays(args)
+	if err != nil {
+		return
+	}
+	err = stmt.wp.opExecute(stmt.stmtHandle, stmt.tx.transHandle, args, stmt.paramXsqlda)
 	if err != nil {
 		return
 	}
@@ -107,13 +155,40 @@ func (stmt *firebirdsqlStmt) exec(ctx context.Context, args []driver.Value) (res
 		rowcount = 0
 	}
 
+	if stmt.stmtType == isc_info_sql_stmt_select {
+		err = stmt.free(1) // DSQL_close
+		if err != nil {
+			return
+		}
+	}
+	if stmt.tx.isAutocommit {
+		err = stmt.tx.Commit()
+		if err != nil {
+			return
+		}
+	}
+
 	result = &firebirdsqlResult{
 		affectedRows: rowcount,
 	}
 	return
 }
 
+func (stmt *firebirdsqlStmt) bindArgs(namedargs []driver.NamedValue) ([]driver.Value, error) {
+	args, err := bindNamedValues(stmt.paramNames, namedargs)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(stmt.paramXsqlda) {
+		return nil, fmt.Errorf("Expected %d arguments, got %d", len(stmt.paramXsqlda), len(args))
+	}
+	return args, nil
+}
+
 func (stmt *firebirdsqlStmt) Exec(args []driver.Value) (result driver.Result, err error) {
+	if len(stmt.paramNames) > 0 {
+		return nil, ErrNamedArgsRequired
+	}
 	return stmt.exec(context.Background(), args)
 }
 
@@ -123,8 +198,17 @@ func (stmt *firebirdsqlStmt) query(ctx context.Context, args []driver.Value) (dr
 	var result []driver.Value
 	var done = make(chan struct{}, 1)
 
+	err = stmt.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args, err = stmt.putArrays(args)
+	if err != nil {
+		return nil, err
+	}
+
 	if stmt.stmtType == isc_info_sql_stmt_exec_procedure {
-		err = stmt.wp.opExecute2(stmt.stmtHandle, stmt.tx.transHandle, args, stmt.blr)
+		err = stmt.wp.opExecute2(stmt.stmtHandle, stmt.tx.transHandle, args, stmt.paramXsqlda, stmt.blr)
 		if err != nil {
 			return nil, err
 		}
@@ -143,7 +227,7 @@ func (stmt *firebirdsqlStmt) query(ctx context.Context, args []driver.Value) (dr
 			return nil, err
 		}
 	} else {
-		err := stmt.wp.opExecute(stmt.stmtHandle, stmt.tx.transHandle, args)
+		err := stmt.wp.opExecute(stmt.stmtHandle, stmt.tx.transHandle, args, stmt.paramXsqlda)
 		if err != nil {
 			return nil, err
 		}
@@ -162,13 +246,21 @@ func (stmt *firebirdsqlStmt) query(ctx context.Context, args []driver.Value) (dr
 }
 
 func (stmt *firebirdsqlStmt) Query(args []driver.Value) (rows driver.Rows, err error) {
+	if len(stmt.paramNames) > 0 {
+		return nil, ErrNamedArgsRequired
+	}
 	return stmt.query(context.Background(), args)
 }
 
-func newFirebirdsqlStmt(fc *firebirdsqlConn, query string) (stmt *firebirdsqlStmt, err error) {
+func newFirebirdsqlStmt(fc *firebirdsqlConn, tx *firebirdsqlTx, query string) (stmt *firebirdsqlStmt, err error) {
 	stmt = new(firebirdsqlStmt)
 	stmt.wp = fc.wp
-	stmt.tx = fc.tx
+	stmt.tx = tx
+
+	query, stmt.paramNames, err = parseNamedParams(query)
+	if err != nil {
+		return nil, err
+	}
 
 	err = fc.wp.opAllocateStatement()
 	if err != nil {
@@ -200,7 +292,7 @@ func newFirebirdsqlStmt(fc *firebirdsqlConn, query string) (stmt *firebirdsqlStm
 		return
 	}
 
-	stmt.stmtType, stmt.xsqlda, err = fc.wp.parse_xsqlda(buf, stmt.stmtHandle)
+	stmt.stmtType, stmt.xsqlda, stmt.paramXsqlda, err = fc.wp.parse_xsqlda(buf, stmt.stmtHandle)
 	if err != nil {
 		return nil, err
 	}
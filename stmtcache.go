@@ -0,0 +1,114 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"container/list"
+	"errors"
+)
+
+// stmtCache is a LRU cache of the statements prepared by Exec and Query of a connection.
+type stmtCache struct {
+	size  int
+	lru   *list.List // of *firebirdsqlStmt, the most recently used first
+	stmts map[string]*list.Element
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		lru:   list.New(),
+		stmts: make(map[string]*list.Element),
+	}
+}
+
+// isCacheable reports whether the statement of the type can be executed again.
+func isCacheable(stmtType int32) bool {
+	switch stmtType {
+	case isc_info_sql_stmt_select,
+		isc_info_sql_stmt_insert,
+		isc_info_sql_stmt_update,
+		isc_info_sql_stmt_delete,
+		isc_info_sql_stmt_exec_procedure,
+		isc_info_sql_stmt_select_for_upd:
+		return true
+	}
+	return false
+}
+
+// get returns the statement of query, or nil if it is not cached or is in use.
+func (c *stmtCache) get(query string) *firebirdsqlStmt {
+	e, ok := c.stmts[query]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	stmt := e.Value.(*firebirdsqlStmt)
+	if stmt.inUse {
+		return nil
+	}
+	return stmt
+}
+
+// put adds the statement, evicting the least recently used one over the size.
+func (c *stmtCache) put(query string, stmt *firebirdsqlStmt) {
+	if _, ok := c.stmts[query]; ok || !isCacheable(stmt.stmtType) {
+		return
+	}
+	stmt.cacheKey = query
+	c.stmts[query] = c.lru.PushFront(stmt)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops the statement, or lets it be dropped when it is released.
+func (c *stmtCache) remove(e *list.Element) {
+	stmt := c.lru.Remove(e).(*firebirdsqlStmt)
+	delete(c.stmts, stmt.cacheKey)
+	stmt.cacheKey = ""
+	if !stmt.inUse {
+		stmt.Close()
+	}
+}
+
+func (c *stmtCache) clear() {
+	for c.lru.Len() > 0 {
+		c.remove(c.lru.Front())
+	}
+}
+
+// invalidatedBy reports whether err is caused by the metadata changed after, or locked by,
+// the cached statements, and clears the cache then.
+func (c *stmtCache) invalidatedBy(err error) bool {
+	var fbErr *FbError
+	if !errors.As(err, &fbErr) || c.lru.Len() == 0 {
+		return false
+	}
+	if !fbErr.HasGDSCode(isc_obsolete_metadata) && !fbErr.HasGDSCode(isc_obj_in_use) {
+		return false
+	}
+	c.clear()
+	return true
+}
@@ -0,0 +1,73 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmtCache(t *testing.T) {
+	c := newStmtCache(2)
+	// statements in use are not dropped on eviction
+	a := &firebirdsqlStmt{stmtType: isc_info_sql_stmt_select, inUse: true}
+	b := &firebirdsqlStmt{stmtType: isc_info_sql_stmt_insert, inUse: true}
+	d := &firebirdsqlStmt{stmtType: isc_info_sql_stmt_update, inUse: true}
+	ddl := &firebirdsqlStmt{stmtType: isc_info_sql_stmt_ddl, inUse: true}
+
+	c.put("a", a)
+	c.put("b", b)
+	c.put("ddl", ddl)
+	assert.Equal(t, "", ddl.cacheKey)
+	assert.Nil(t, c.get("a"), "in use")
+	a.inUse = false
+	assert.Equal(t, a, c.get("a"))
+
+	// b is the least recently used, and is dropped when it is released
+	c.put("d", d)
+	assert.Equal(t, 2, c.lru.Len())
+	assert.Equal(t, "a", a.cacheKey)
+	assert.Equal(t, "d", d.cacheKey)
+	assert.Nil(t, c.get("b"))
+	assert.Equal(t, "", b.cacheKey)
+}
+
+func TestStmtCacheInvalidated(t *testing.T) {
+	c := newStmtCache(2)
+	c.put("a", &firebirdsqlStmt{stmtType: isc_info_sql_stmt_select, inUse: true})
+
+	assert.False(t, c.invalidatedBy(nil))
+	assert.False(t, c.invalidatedBy(errors.New("object in use")))
+	assert.False(t, c.invalidatedBy(&FbError{GDSCodes: []int{335544665}}))
+	assert.Equal(t, 1, c.lru.Len())
+
+	err := fmt.Errorf("wrapped: %w", &FbError{GDSCodes: []int{335544351, isc_obj_in_use}, Message: "unsuccessful metadata update\nobject TABLE \"T\" is in use\n"})
+	assert.True(t, c.invalidatedBy(err))
+	assert.Equal(t, 0, c.lru.Len())
+	// nothing to retry with the empty cache
+	assert.False(t, c.invalidatedBy(err))
+}
@@ -23,7 +23,13 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
 package firebirdsql
 
-func getTimezoneNameByID(id int) string {
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var timezoneNameByID = func() map[int]string {
 	return map[int]string{
 		65535: "GMT",
 		65534: "ACT",
@@ -659,5 +665,64 @@ func getTimezoneNameByID(id int) string {
 		64904: "Zulu",
 		64903: "America/Nuuk",
 		64902: "Asia/Qostanay",
-	}[id]
+		64901: "Pacific/Kanton",
+		64900: "Europe/Kyiv",
+		64899: "America/Ciudad_Juarez",
+	}
+}()
+
+var (
+	timezoneIDByName     map[string]int
+	timezoneIDByNameOnce sync.Once
+)
+
+func getTimezoneNameByID(id int) string {
+	return timezoneNameByID[id]
+}
+
+func getTimezoneIDByName(name string) (int, bool) {
+	timezoneIDByNameOnce.Do(func() {
+		timezoneIDByName = make(map[string]int, len(timezoneNameByID))
+		for id, name := range timezoneNameByID {
+			timezoneIDByName[name] = id
+		}
+	})
+	id, ok := timezoneIDByName[name]
+	return id, ok
+}
+
+// loadTimezone loads a region name or a "+HH:MM" offset.
+func loadTimezone(name string) (*time.Location, error) {
+	var sign byte
+	var h, m int
+	if n, _ := fmt.Sscanf(name, "%c%d:%d", &sign, &h, &m); n == 3 && (sign == '+' || sign == '-') {
+		offset := h*60 + m
+		if sign == '-' {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset*60), nil
+	}
+	tz, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Can not load time zone %s: %v", name, err)
+	}
+	return tz, nil
+}
+
+// getTimezoneByID returns the location of a time zone id.
+// Ids from 0 to 2878 are offsets from -23:59 to +23:59.
+func getTimezoneByID(id int) (*time.Location, error) {
+	if name, ok := timezoneNameByID[id]; ok {
+		return loadTimezone(name)
+	}
+	if id >= 0 && id <= 2*timezoneOffsetBase {
+		offset := id - timezoneOffsetBase
+		sign := '+'
+		if offset < 0 {
+			sign = '-'
+			offset = -offset
+		}
+		return time.FixedZone(fmt.Sprintf("%c%02d:%02d", sign, offset/60, offset%60), (id-timezoneOffsetBase)*60), nil
+	}
+	return nil, fmt.Errorf("Unknown time zone id %d", id)
 }
@@ -24,54 +24,18 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 type firebirdsqlTx struct {
-	fc             *firebirdsqlConn
-	isolationLevel int
-	isAutocommit   bool
-	transHandle    int32
-	needBegin      bool
+	fc           *firebirdsqlConn
+	opts         TxOptions
+	isAutocommit bool
+	transHandle  int32
+	needBegin    bool
+	savepoints   []string
 }
 
 func (tx *firebirdsqlTx) begin() (err error) {
-	var tpb []byte
-	switch tx.isolationLevel {
-	case ISOLATION_LEVEL_READ_COMMITED_LEGACY:
-		tpb = []byte{
-			byte(isc_tpb_version3),
-			byte(isc_tpb_write),
-			byte(isc_tpb_wait),
-			byte(isc_tpb_read_committed),
-			byte(isc_tpb_no_rec_version),
-		}
-	case ISOLATION_LEVEL_READ_COMMITED:
-		tpb = []byte{
-			byte(isc_tpb_version3),
-			byte(isc_tpb_write),
-			byte(isc_tpb_wait),
-			byte(isc_tpb_read_committed),
-			byte(isc_tpb_rec_version),
-		}
-	case ISOLATION_LEVEL_REPEATABLE_READ:
-		tpb = []byte{
-			byte(isc_tpb_version3),
-			byte(isc_tpb_write),
-			byte(isc_tpb_wait),
-			byte(isc_tpb_concurrency),
-		}
-	case ISOLATION_LEVEL_SERIALIZABLE:
-		tpb = []byte{
-			byte(isc_tpb_version3),
-			byte(isc_tpb_write),
-			byte(isc_tpb_wait),
-			byte(isc_tpb_consistency),
-		}
-	case ISOLATION_LEVEL_READ_COMMITED_RO:
-		tpb = []byte{
-			byte(isc_tpb_version3),
-			byte(isc_tpb_read),
-			byte(isc_tpb_wait),
-			byte(isc_tpb_read_committed),
-			byte(isc_tpb_rec_version),
-		}
+	tpb, err := tx.opts.tpb()
+	if err != nil {
+		return
 	}
 	err = tx.fc.wp.opTransaction(tpb)
 	if err != nil {
@@ -79,28 +43,63 @@ func (tx *firebirdsqlTx) begin() (err error) {
 	}
 	tx.transHandle, _, _, err = tx.fc.wp.opResponse()
 	tx.needBegin = false
-	tx.fc.transactionSet[tx] = struct{}{}
+	if err == nil {
+		tx.fc.transactionSet[tx] = struct{}{}
+	}
 	return
 }
 
-func (tx *firebirdsqlTx) commitRetainging() (err error) {
+func (tx *firebirdsqlTx) commitRetaining() (err error) {
 	err = tx.fc.wp.opCommitRetaining(tx.transHandle)
 	if err != nil {
 		return
 	}
 	_, _, _, err = tx.fc.wp.opResponse()
-	tx.isAutocommit = tx.fc.isAutocommit
+	tx.savepoints = nil
 	return
 }
 
-func (tx *firebirdsqlTx) Commit() (err error) {
+func (tx *firebirdsqlTx) rollbackRetaining() (err error) {
+	err = tx.fc.wp.opRollbackRetaining(tx.transHandle)
+	if err != nil {
+		return
+	}
+	_, _, _, err = tx.fc.wp.opResponse()
+	tx.savepoints = nil
+	return
+}
+
+func (tx *firebirdsqlTx) snapshotNumber() (int64, error) {
+	err := tx.fc.wp.opInfoTransaction(tx.transHandle, []byte{fb_info_tra_snapshot_number, isc_info_end})
+	if err != nil {
+		return 0, err
+	}
+	_, _, buf, err := tx.fc.wp.opResponse()
+	if err != nil {
+		return 0, err
+	}
+	return parseInfo(buf, fb_info_tra_snapshot_number)
+}
+
+func (tx *firebirdsqlTx) commit() (err error) {
 	err = tx.fc.wp.opCommit(tx.transHandle)
 	if err != nil {
 		return err
 	}
 	_, _, _, err = tx.fc.wp.opResponse()
-	tx.isAutocommit = tx.fc.isAutocommit
+	return
+}
+
+func (tx *firebirdsqlTx) Commit() (err error) {
+	err = tx.commit()
+	if c := tx.fc.stmtCache; c != nil && c.invalidatedBy(err) {
+		// DDL is done on commit, when the cached statements lock the metadata
+		err = tx.commit()
+	}
+	tx.opts, tx.isAutocommit = tx.fc.implicitTx()
+	tx.savepoints = nil
 	tx.needBegin = true
+	delete(tx.fc.transactionSet, tx)
 	return
 }
 
@@ -110,15 +109,17 @@ func (tx *firebirdsqlTx) Rollback() (err error) {
 		return nil
 	}
 	_, _, _, err = tx.fc.wp.opResponse()
-	tx.isAutocommit = tx.fc.isAutocommit
+	tx.opts, tx.isAutocommit = tx.fc.implicitTx()
+	tx.savepoints = nil
 	tx.needBegin = true
+	delete(tx.fc.transactionSet, tx)
 	return
 }
 
-func newFirebirdsqlTx(fc *firebirdsqlConn, isolationLevel int, isAutocommit bool, withBegin bool) (tx *firebirdsqlTx, err error) {
+func newFirebirdsqlTx(fc *firebirdsqlConn, opts TxOptions, isAutocommit bool, withBegin bool) (tx *firebirdsqlTx, err error) {
 	tx = new(firebirdsqlTx)
 	tx.fc = fc
-	tx.isolationLevel = isolationLevel
+	tx.opts = opts
 	tx.isAutocommit = isAutocommit
 	tx.needBegin = false
 
@@ -24,9 +24,15 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTransaction(t *testing.T) {
@@ -436,3 +442,558 @@ func TestIssue89(t *testing.T) {
 	conn2.Close()
 
 }
+
+func TestTransactionContext(t *testing.T) {
+	fc := &firebirdsqlConn{transactionSet: make(map[*firebirdsqlTx]struct{})}
+	fc.tx = &firebirdsqlTx{fc: fc}
+	other := &firebirdsqlConn{}
+
+	ctx := context.Background()
+	tx, err := fc.transaction(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fc.tx, tx)
+
+	trans := &Transaction{tx: &firebirdsqlTx{fc: fc}}
+	tx, err = fc.transaction(WithTransaction(ctx, trans))
+	require.NoError(t, err)
+	assert.Equal(t, trans.tx, tx)
+
+	_, err = other.transaction(WithTransaction(ctx, trans))
+	assert.ErrorIs(t, err, ErrTransactionConnection)
+
+	trans.done = true
+	_, err = fc.transaction(WithTransaction(ctx, trans))
+	assert.ErrorIs(t, err, sql.ErrTxDone)
+	assert.ErrorIs(t, trans.Commit(), sql.ErrTxDone)
+
+	// a statement of a transaction ignores the transaction of the context
+	stmt := &firebirdsqlStmt{tx: trans.tx, fixedTx: true}
+	require.NoError(t, stmt.begin(WithTransaction(ctx, &Transaction{tx: fc.tx})))
+	assert.Equal(t, trans.tx, stmt.tx)
+	stmt.trans = trans
+	assert.ErrorIs(t, stmt.begin(ctx), sql.ErrTxDone)
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_concurrent_transactions_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_trans (id INTEGER)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c.Close()
+
+	snapshot, err := BeginTransaction(ctx, c, TxOptions{Isolation: sql.LevelRepeatableRead})
+	require.NoError(t, err)
+	writer, err := BeginTransaction(ctx, c, TxOptions{})
+	require.NoError(t, err)
+
+	var n int
+	err = snapshot.QueryRowContext(ctx, "SELECT count(*) FROM test_trans").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = writer.ExecContext(ctx, "INSERT INTO test_trans (id) VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	// the snapshot does not see the row committed after it started
+	err = c.QueryRowContext(WithTransaction(ctx, snapshot), "SELECT count(*) FROM test_trans").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	err = c.QueryRowContext(ctx, "SELECT count(*) FROM test_trans").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// a statement of the snapshot runs in it whatever the context
+	stmt, err := snapshot.PrepareContext(ctx, "SELECT count(*) FROM test_trans")
+	require.NoError(t, err)
+	require.NoError(t, stmt.QueryRowContext(ctx).Scan(&n))
+	assert.Equal(t, 0, n)
+
+	// the transactions and the connection are used from several goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			var n int
+			assert.NoError(t, stmt.QueryRowContext(ctx).Scan(&n))
+			assert.Equal(t, 0, n)
+		}()
+		go func() {
+			defer wg.Done()
+			var n int
+			assert.NoError(t, c.QueryRowContext(ctx, "SELECT count(*) FROM test_trans").Scan(&n))
+			assert.Equal(t, 1, n)
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, stmt.Close())
+	require.NoError(t, snapshot.Commit())
+
+	_, err = c.ExecContext(WithTransaction(ctx, writer), "INSERT INTO test_trans (id) VALUES (2)")
+	assert.ErrorIs(t, err, sql.ErrTxDone)
+	_, err = writer.ExecContext(ctx, "INSERT INTO test_trans (id) VALUES (2)")
+	assert.ErrorIs(t, err, sql.ErrTxDone)
+}
+
+func TestTxOptionsTpb(t *testing.T) {
+	tpb, err := TxOptions{}.tpb()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{isc_tpb_version3, isc_tpb_write, isc_tpb_wait, isc_tpb_read_committed, isc_tpb_rec_version}, tpb)
+
+	tpb, err = TxOptions{
+		Isolation:   sql.LevelRepeatableRead,
+		ReadOnly:    true,
+		LockTimeout: 3,
+		Reservations: []TableReservation{
+			{Table: "foo", Mode: LockProtected, Write: true},
+			{Table: `"Bar"`},
+		},
+		NoAutoUndo:  true,
+		IgnoreLimbo: true,
+		AutoCommit:  true,
+	}.tpb()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		isc_tpb_version3, isc_tpb_read, isc_tpb_wait, isc_tpb_lock_timeout, 4, 3, 0, 0, 0,
+		isc_tpb_concurrency,
+		isc_tpb_lock_write, 3, 'F', 'O', 'O', isc_tpb_protected,
+		isc_tpb_lock_read, 3, 'B', 'a', 'r', isc_tpb_shared,
+		isc_tpb_no_auto_undo, isc_tpb_ignore_limbo, isc_tpb_autocommit,
+	}, tpb)
+
+	tpb, err = TxOptions{NoWait: true, ReadCommitted: ReadConsistency}.tpb()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{isc_tpb_version3, isc_tpb_write, isc_tpb_nowait, isc_tpb_read_committed, isc_tpb_read_consistency}, tpb)
+
+	_, err = TxOptions{NoWait: true, LockTimeout: 1}.tpb()
+	assert.ErrorIs(t, err, ErrLockTimeoutNoWait)
+	_, err = TxOptions{Reservations: []TableReservation{{}}}.tpb()
+	assert.Error(t, err)
+	_, err = TxOptions{Reservations: []TableReservation{{Table: `""`}}}.tpb()
+	assert.Error(t, err)
+	assert.Equal(t, `a"b`, storedTableName(`"a""b"`))
+
+	tpb, err = TxOptions{Isolation: sql.LevelSnapshot}.tpb()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{isc_tpb_version3, isc_tpb_write, isc_tpb_wait, isc_tpb_concurrency}, tpb)
+	_, err = TxOptions{Isolation: sql.LevelReadUncommitted}.tpb()
+	assert.NoError(t, err)
+	_, err = TxOptions{Isolation: sql.LevelLinearizable}.tpb()
+	assert.ErrorIs(t, err, ErrIsolationLevelNotSupported)
+
+	tpb, err = TxOptions{ReadOnly: true, AtSnapshotNumber: 0x100000002}.tpb()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{isc_tpb_version3, isc_tpb_read, isc_tpb_wait, isc_tpb_concurrency, isc_tpb_at_snapshot_number, 8, 2, 0, 0, 0, 1, 0, 0, 0}, tpb)
+	_, err = TxOptions{Isolation: sql.LevelReadCommitted, AtSnapshotNumber: 1}.tpb()
+	assert.ErrorIs(t, err, ErrSnapshotNumberIsolation)
+}
+
+func TestTxOptionsOf(t *testing.T) {
+	dsn, err := parseDSN("user:password@localhost/dbname?tx_isolation=snapshot&tx_read_only=true&tx_wait=false")
+	require.NoError(t, err)
+	defaults, err := dsn.txOptions()
+	require.NoError(t, err)
+	assert.Equal(t, TxOptions{Isolation: sql.LevelSnapshot, ReadOnly: true, NoWait: true}, defaults)
+	fc := &firebirdsqlConn{txOptions: defaults}
+
+	ctx := context.Background()
+	assert.Equal(t, defaults, fc.txOptionsOf(ctx, driver.TxOptions{}))
+	assert.Equal(t, TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, NoWait: true},
+		fc.txOptionsOf(ctx, driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable)}))
+
+	assert.Equal(t, TxOptions{AtSnapshotNumber: 5},
+		fc.txOptionsOf(WithTxOptions(ctx, TxOptions{AtSnapshotNumber: 5}), driver.TxOptions{}))
+
+	ctx = WithTxOptions(ctx, TxOptions{LockTimeout: 3})
+	assert.Equal(t, TxOptions{Isolation: sql.LevelSnapshot, LockTimeout: 3}, fc.txOptionsOf(ctx, driver.TxOptions{}))
+	assert.Equal(t, TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: true, LockTimeout: 3},
+		fc.txOptionsOf(ctx, driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelReadCommitted), ReadOnly: true}))
+
+	dsn, err = parseDSN("user:password@localhost/dbname?tx_isolation=read_consistency&tx_lock_timeout=5")
+	require.NoError(t, err)
+	opts, err := dsn.txOptions()
+	require.NoError(t, err)
+	assert.Equal(t, TxOptions{Isolation: sql.LevelReadCommitted, ReadCommitted: ReadConsistency, LockTimeout: 5}, opts)
+
+	_, err = parseDSN("user:password@localhost/dbname?tx_isolation=dirty")
+	assert.ErrorIs(t, err, ErrDsnInvalidTxIsolation)
+	_, err = parseDSN("user:password@localhost/dbname?tx_lock_timeout=-1")
+	assert.ErrorIs(t, err, ErrDsnInvalidLockTimeout)
+	_, err = parseDSN("user:password@localhost/dbname?tx_lock_timeout=1&tx_wait=false")
+	assert.ErrorIs(t, err, ErrLockTimeoutNoWait)
+}
+
+func TestTxNoWait(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_tx_nowait_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_nowait (id INTEGER NOT NULL PRIMARY KEY, n INTEGER)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_nowait (id, n) VALUES (1, 0)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	holder, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer holder.Rollback()
+	_, err = holder.Exec("UPDATE test_nowait SET n = 1 WHERE id = 1")
+	require.NoError(t, err)
+
+	for _, opts := range []TxOptions{{NoWait: true}, {LockTimeout: 1}} {
+		tx, err := conn.BeginTx(WithTxOptions(ctx, opts), nil)
+		require.NoError(t, err)
+		start := time.Now()
+		_, err = tx.Exec("UPDATE test_nowait SET n = 2 WHERE id = 1")
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		require.NoError(t, tx.Rollback())
+	}
+}
+
+func TestTxDefaultOptions(t *testing.T) {
+	testDSN := GetTestDSN("test_tx_default_options_")
+	conn, err := sql.Open("firebirdsql_createdb", testDSN)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_default (id INTEGER)")
+	require.NoError(t, err)
+
+	reporting, err := sql.Open("firebirdsql", testDSN+"?tx_isolation=snapshot&tx_read_only=true")
+	require.NoError(t, err)
+	defer reporting.Close()
+
+	_, err = reporting.Exec("INSERT INTO test_default (id) VALUES (1)")
+	assert.Error(t, err, "autocommit transactions are read only")
+
+	tx, err := reporting.Begin()
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, tx.QueryRow("SELECT count(*) FROM test_default").Scan(&n))
+	_, err = conn.Exec("INSERT INTO test_default (id) VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, tx.QueryRow("SELECT count(*) FROM test_default").Scan(&n))
+	assert.Equal(t, 0, n, "snapshot does not see the row committed after it started")
+	require.NoError(t, tx.Commit())
+
+	_, err = reporting.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelLinearizable})
+	assert.ErrorIs(t, err, ErrIsolationLevelNotSupported)
+}
+
+func TestSavepoint(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_savepoint_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_savepoint (id INTEGER)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c.Close()
+	tr, err := BeginTransaction(ctx, c, TxOptions{})
+	require.NoError(t, err)
+	trCtx := WithTransaction(ctx, tr)
+	count := func() (n int) {
+		require.NoError(t, c.QueryRowContext(trCtx, "SELECT count(*) FROM test_savepoint").Scan(&n))
+		return
+	}
+
+	_, err = c.ExecContext(trCtx, "INSERT INTO test_savepoint (id) VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, tr.Savepoint("first"))
+	_, err = c.ExecContext(trCtx, "INSERT INTO test_savepoint (id) VALUES (2)")
+	require.NoError(t, err)
+	require.NoError(t, tr.Savepoint(`second "one"`))
+	_, err = c.ExecContext(trCtx, "INSERT INTO test_savepoint (id) VALUES (3)")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count())
+
+	require.NoError(t, tr.RollbackTo("first"))
+	assert.Equal(t, 1, count())
+	assert.ErrorIs(t, tr.Release(`second "one"`), ErrSavepointNotActive)
+	require.NoError(t, tr.Release("first"))
+	assert.ErrorIs(t, tr.RollbackTo("first"), ErrSavepointNotActive)
+	assert.ErrorIs(t, tr.Savepoint(""), ErrInvalidSavepointName)
+	require.NoError(t, tr.Commit())
+	assert.ErrorIs(t, tr.Savepoint("first"), sql.ErrTxDone)
+
+	tx, err := conn.Begin()
+	require.NoError(t, err)
+	err = WithSavepoint(ctx, tx, func(ctx context.Context) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test_savepoint (id) VALUES (4)")
+		require.NoError(t, err)
+		innerErr := WithSavepoint(ctx, tx, func(ctx context.Context) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO test_savepoint (id) VALUES (5)")
+			require.NoError(t, err)
+			return sql.ErrNoRows
+		})
+		assert.ErrorIs(t, innerErr, sql.ErrNoRows)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	var ids []int
+	rows, err := conn.Query("SELECT id FROM test_savepoint ORDER BY id")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []int{1, 4}, ids)
+}
+
+func TestDistributedTransaction(t *testing.T) {
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for _, prefix := range []string{"test_distributed_from_", "test_distributed_to_"} {
+		db, err := sql.Open("firebirdsql_createdb", GetTestDSN(prefix))
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = db.Exec("CREATE TABLE account (id INTEGER NOT NULL PRIMARY KEY, balance INTEGER NOT NULL CHECK (balance >= 0))")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO account (id, balance) VALUES (1, 100)")
+		require.NoError(t, err)
+		c, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer c.Close()
+		conns = append(conns, c)
+	}
+	balance := func(c *sql.Conn) (n int) {
+		require.NoError(t, c.QueryRowContext(ctx, "SELECT balance FROM account WHERE id = 1").Scan(&n))
+		return
+	}
+
+	d, err := BeginDistributed(ctx, conns, TxOptions{})
+	require.NoError(t, err)
+	_, err = conns[0].ExecContext(WithTransaction(ctx, d.Transaction(0)), "UPDATE account SET balance = balance - 30 WHERE id = 1")
+	require.NoError(t, err)
+	_, err = conns[1].ExecContext(WithTransaction(ctx, d.Transaction(1)), "UPDATE account SET balance = balance + 30 WHERE id = 1")
+	require.NoError(t, err)
+	require.NoError(t, d.Commit(ctx))
+	assert.Equal(t, 70, balance(conns[0]))
+	assert.Equal(t, 130, balance(conns[1]))
+
+	// a prepared transaction is in limbo until it is committed or rolled back
+	d, err = BeginDistributed(ctx, conns, TxOptions{})
+	require.NoError(t, err)
+	_, err = conns[0].ExecContext(WithTransaction(ctx, d.Transaction(0)), "UPDATE account SET balance = balance - 30 WHERE id = 1")
+	require.NoError(t, err)
+	require.NoError(t, d.Transaction(0).Prepare(ctx, []byte("test")))
+	var limbo []LimboTransaction
+	err = conns[0].Raw(func(driverConn any) (err error) {
+		// the descriptions are read with blob streaming enabled too
+		limbo, err = driverConn.(RawConn).LimboTransactions(WithBlobStreaming(ctx, true))
+		return
+	})
+	require.NoError(t, err)
+	require.Len(t, limbo, 1)
+	assert.Equal(t, []byte("test"), limbo[0].Description)
+	require.NoError(t, d.Rollback())
+	assert.Equal(t, 70, balance(conns[0]))
+	assert.Equal(t, 130, balance(conns[1]))
+}
+
+func TestImplicitTx(t *testing.T) {
+	fc := &firebirdsqlConn{txOptions: TxOptions{Isolation: sql.LevelSnapshot}, isAutocommit: true}
+	opts, isAutocommit := fc.implicitTx()
+	assert.Equal(t, TxOptions{Isolation: sql.LevelSnapshot}, opts)
+	assert.True(t, isAutocommit)
+
+	fc.serverAutocommit = true
+	opts, isAutocommit = fc.implicitTx()
+	assert.Equal(t, TxOptions{Isolation: sql.LevelSnapshot, AutoCommit: true}, opts)
+	assert.False(t, isAutocommit)
+
+	_, err := parseDSN("user:password@localhost/dbname?server_autocommit=true&tx_isolation=snapshot")
+	assert.ErrorIs(t, err, ErrDsnServerAutocommitIsolation)
+	_, err = parseDSN("user:password@localhost/dbname?server_autocommit=true&tx_isolation=read_consistency")
+	assert.NoError(t, err)
+}
+
+func TestServerAutocommit(t *testing.T) {
+	testDSN := GetTestDSN("test_server_autocommit_")
+	conn, err := sql.Open("firebirdsql_createdb", testDSN+"?server_autocommit=true")
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_autocommit (id INTEGER)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_autocommit (id) VALUES (1)")
+	require.NoError(t, err)
+
+	other, err := sql.Open("firebirdsql", testDSN)
+	require.NoError(t, err)
+	defer other.Close()
+	var n int
+	require.NoError(t, other.QueryRow("SELECT count(*) FROM test_autocommit").Scan(&n))
+	assert.Equal(t, 1, n, "the server commits each statement")
+
+	// the transaction is kept when the connection is taken from the pool again
+	conn.SetMaxOpenConns(1)
+	var id1, id2 int64
+	require.NoError(t, conn.QueryRow("SELECT CURRENT_TRANSACTION FROM RDB$DATABASE").Scan(&id1))
+	_, err = conn.Exec("INSERT INTO test_autocommit (id) VALUES (3)")
+	require.NoError(t, err)
+	require.NoError(t, conn.QueryRow("SELECT CURRENT_TRANSACTION FROM RDB$DATABASE").Scan(&id2))
+	assert.Equal(t, id1, id2)
+	_, err = conn.Exec("DELETE FROM test_autocommit WHERE id = 3")
+	require.NoError(t, err)
+
+	tx, err := conn.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO test_autocommit (id) VALUES (2)")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, conn.QueryRow("SELECT count(*) FROM test_autocommit").Scan(&n))
+	assert.Equal(t, 1, n)
+}
+
+func TestCommitRetaining(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_commit_retaining_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_retaining (id INTEGER)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_retaining (id) VALUES (1)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_retaining (id) VALUES (2)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c.Close()
+	tr, err := BeginTransaction(ctx, c, TxOptions{})
+	require.NoError(t, err)
+	trCtx := WithTransaction(ctx, tr)
+
+	rows, err := c.QueryContext(trCtx, "SELECT id FROM test_retaining ORDER BY id")
+	require.NoError(t, err)
+	var ids []int
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		_, err = c.ExecContext(trCtx, "UPDATE test_retaining SET id = id + 10 WHERE id = ?", id)
+		require.NoError(t, err)
+		require.NoError(t, tr.CommitRetaining(), "the cursor stays open")
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []int{1, 2}, ids)
+
+	_, err = c.ExecContext(trCtx, "DELETE FROM test_retaining")
+	require.NoError(t, err)
+	require.NoError(t, tr.RollbackRetaining())
+	require.NoError(t, tr.Commit())
+	assert.ErrorIs(t, tr.CommitRetaining(), sql.ErrTxDone)
+
+	var n int
+	require.NoError(t, conn.QueryRow("SELECT count(*) FROM test_retaining WHERE id > 10").Scan(&n))
+	assert.Equal(t, 2, n)
+}
+
+func TestSharedSnapshot(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_shared_snapshot_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_snapshot (id INTEGER)")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO test_snapshot (id) VALUES (1)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c.Close()
+	tr, err := BeginTransaction(ctx, c, TxOptions{Isolation: sql.LevelSnapshot, ReadOnly: true})
+	require.NoError(t, err)
+	defer tr.Commit()
+	n, err := tr.SnapshotNumber()
+	require.NoError(t, err)
+	assert.NotZero(t, n)
+	queried, err := SnapshotNumber(WithTransaction(ctx, tr), c)
+	require.NoError(t, err)
+	assert.Equal(t, n, queried)
+
+	_, err = conn.Exec("INSERT INTO test_snapshot (id) VALUES (2)")
+	require.NoError(t, err)
+
+	// another connection sees the snapshot of tr
+	tx, err := conn.BeginTx(WithTxOptions(ctx, TxOptions{ReadOnly: true, AtSnapshotNumber: n}), nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var count int
+	require.NoError(t, tx.QueryRow("SELECT count(*) FROM test_snapshot").Scan(&count))
+	assert.Equal(t, 1, count)
+	shared, err := SnapshotNumber(ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, n, shared)
+}
+
+func TestWithSavepointNesting(t *testing.T) {
+	conn, err := sql.Open("firebirdsql_createdb", GetTestDSN("test_savepoint_nesting_"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("CREATE TABLE test_nesting (id INTEGER)")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	insert := func(ctx context.Context, id int) {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test_nesting (id) VALUES (?)", id)
+		require.NoError(t, err)
+	}
+
+	err = WithSavepoint(ctx, tx, func(outer context.Context) error {
+		insert(outer, 1)
+		// siblings
+		assert.NoError(t, WithSavepoint(outer, tx, func(ctx context.Context) error {
+			insert(ctx, 2)
+			return nil
+		}))
+		assert.ErrorIs(t, WithSavepoint(outer, tx, func(ctx context.Context) error {
+			insert(ctx, 3)
+			return sql.ErrNoRows
+		}), sql.ErrNoRows)
+		// the context of the caller instead of the one given to f
+		assert.ErrorIs(t, WithSavepoint(ctx, tx, func(context.Context) error {
+			insert(ctx, 4)
+			return sql.ErrNoRows
+		}), sql.ErrNoRows)
+		insert(outer, 5)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = WithSavepoint(ctx, tx, func(outer context.Context) error {
+		insert(outer, 6)
+		assert.NoError(t, WithSavepoint(ctx, tx, func(context.Context) error {
+			insert(ctx, 7)
+			return nil
+		}))
+		return sql.ErrNoRows
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, tx.Commit())
+
+	var ids []int
+	rows, err := conn.Query("SELECT id FROM test_nesting ORDER BY id")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []int{1, 2, 5}, ids)
+}
@@ -0,0 +1,279 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func (tx *firebirdsqlTx) prepare(description []byte) (err error) {
+	err = tx.fc.wp.opPrepare2(tx.transHandle, description)
+	if err != nil {
+		return
+	}
+	_, _, _, err = tx.fc.wp.opResponse()
+	return
+}
+
+// Prepare is the first phase of the two-phase commit. After Prepare, the transaction
+// is only committed or rolled back, and it stays in limbo when the connection is lost.
+// The description is stored in RDB$TRANSACTIONS to recover the transaction.
+func (t *Transaction) Prepare(ctx context.Context, description []byte) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.raw(func() error {
+		return t.tx.prepare(description)
+	})
+}
+
+// DistributedTransaction is a transaction over connections to several databases,
+// which is committed by the two-phase commit.
+//
+//	d, err := firebirdsql.BeginDistributed(ctx, []*sql.Conn{from, to}, firebirdsql.TxOptions{})
+//	_, err = d.Transaction(0).ExecContext(ctx, debit, amount)
+//	_, err = d.Transaction(1).ExecContext(ctx, credit, amount)
+//	err = d.Commit(ctx)
+type DistributedTransaction struct {
+	id           string
+	transactions []*Transaction
+}
+
+// BeginDistributed begins a transaction with opts on each connection of conns.
+func BeginDistributed(ctx context.Context, conns []*sql.Conn, opts TxOptions) (*DistributedTransaction, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	d := &DistributedTransaction{id: hex.EncodeToString(id)}
+	for _, conn := range conns {
+		t, err := BeginTransaction(ctx, conn, opts)
+		if err != nil {
+			d.Rollback()
+			return nil, err
+		}
+		d.transactions = append(d.transactions, t)
+	}
+	return d, nil
+}
+
+// ID returns the random id of d, which is in the descriptions of its prepared transactions.
+func (d *DistributedTransaction) ID() string {
+	return d.id
+}
+
+// Transaction returns the transaction of d on the i-th connection given to BeginDistributed.
+func (d *DistributedTransaction) Transaction(i int) *Transaction {
+	return d.transactions[i]
+}
+
+// Commit prepares all the transactions of d, then commits them. When a transaction fails to
+// prepare, or ctx is done before all are prepared, all the transactions are rolled back.
+// An error of the second phase leaves the transaction in limbo, to be recovered with
+// RawConn.LimboTransactions, CommitLimbo and RollbackLimbo.
+func (d *DistributedTransaction) Commit(ctx context.Context) error {
+	for i, t := range d.transactions {
+		description := []byte(fmt.Sprintf("firebirdsql %s %d/%d", d.id, i+1, len(d.transactions)))
+		if err := t.Prepare(ctx, description); err != nil {
+			d.Rollback()
+			return err
+		}
+	}
+
+	var err error
+	for i, t := range d.transactions {
+		if commitErr := t.Commit(); commitErr != nil && err == nil {
+			err = fmt.Errorf("Commit of the transaction %d of %s failed: %w", i, d.id, commitErr)
+		}
+	}
+	return err
+}
+
+// Rollback rolls back all the transactions of d.
+func (d *DistributedTransaction) Rollback() error {
+	var err error
+	for _, t := range d.transactions {
+		if rollbackErr := t.Rollback(); rollbackErr != nil && rollbackErr != sql.ErrTxDone && err == nil {
+			err = rollbackErr
+		}
+	}
+	return err
+}
+
+// transactionIdBytes returns the little endian bytes of a transaction id, 8 bytes for
+// the ids which are negative as 4 bytes.
+func transactionIdBytes(id int64) []byte {
+	if id > 0x7FFFFFFF {
+		return append(int32_to_bytes(int32(id)), int32_to_bytes(int32(id>>32))...)
+	}
+	return int32_to_bytes(int32(id))
+}
+
+// parseLimboInfo returns the transaction ids of the isc_info_limbo items of buf.
+func parseLimboInfo(buf []byte) ([]int64, error) {
+	var ids []int64
+	for i := 0; i < len(buf) && buf[i] != isc_info_end; {
+		if buf[i] == isc_info_truncated || i+3 > len(buf) {
+			return nil, errors.New("Too many transactions in limbo")
+		}
+		ln := int(bytes_to_int16(buf[i+1 : i+3]))
+		if i+3+ln > len(buf) {
+			return nil, errors.New("Too many transactions in limbo")
+		}
+		if buf[i] == isc_info_limbo {
+			var id int64
+			for j := ln - 1; j >= 0; j-- {
+				id = id<<8 | int64(buf[i+3+j])
+			}
+			ids = append(ids, id)
+		}
+		i += 3 + ln
+	}
+	return ids, nil
+}
+
+// LimboTransaction is a transaction in limbo.
+type LimboTransaction struct {
+	ID int64
+	// Description is the description given to Transaction.Prepare, nil without it.
+	Description []byte
+}
+
+const limboDescriptionQuery = "SELECT RDB$TRANSACTION_ID, RDB$TRANSACTION_DESCRIPTION FROM RDB$TRANSACTIONS"
+
+// limboDescriptions returns the descriptions of the prepared transactions by id,
+// read in a transaction of their own.
+func (fc *firebirdsqlConn) limboDescriptions(ctx context.Context) (descriptions map[int64][]byte, err error) {
+	tx, err := newFirebirdsqlTx(fc, TxOptions{ReadOnly: true}, false, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil {
+			err = rollbackErr
+		}
+	}()
+
+	s, err := newFirebirdsqlStmt(fc, tx, limboDescriptionQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer s.free(2) // DSQL_drop
+	s.fixedTx = true
+	// the descriptions are read as []byte whatever the blob streaming of ctx
+	rows, err := s.query(WithBlobStreaming(ctx, false), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	descriptions = make(map[int64][]byte)
+	dest := make([]driver.Value, 2)
+	for {
+		if err = rows.Next(dest); err == io.EOF {
+			return descriptions, nil
+		} else if err != nil {
+			return nil, err
+		}
+		id := int64(metadataInt(dest[0]))
+		switch description := dest[1].(type) {
+		case nil:
+		case []byte:
+			descriptions[id] = description
+		default:
+			return nil, fmt.Errorf("Unexpected description %T of transaction %d", description, id)
+		}
+	}
+}
+
+func (fc *firebirdsqlConn) LimboTransactions(ctx context.Context) ([]LimboTransaction, error) {
+	err := fc.wp.opInfoDatabase([]byte{isc_info_limbo, isc_info_end})
+	if err != nil {
+		return nil, err
+	}
+	_, _, buf, err := fc.wp.opResponse()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := parseLimboInfo(buf)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	descriptions, err := fc.limboDescriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limbo := make([]LimboTransaction, len(ids))
+	for i, id := range ids {
+		limbo[i] = LimboTransaction{ID: id, Description: descriptions[id]}
+	}
+	return limbo, nil
+}
+
+// reconnect returns the handle of the transaction in limbo of id.
+func (fc *firebirdsqlConn) reconnect(id int64) (int32, error) {
+	err := fc.wp.opReconnect(id)
+	if err != nil {
+		return 0, err
+	}
+	transHandle, _, _, err := fc.wp.opResponse()
+	return transHandle, err
+}
+
+func (fc *firebirdsqlConn) CommitLimbo(ctx context.Context, id int64) error {
+	transHandle, err := fc.reconnect(id)
+	if err != nil {
+		return err
+	}
+	err = fc.wp.opCommit(transHandle)
+	if err != nil {
+		return err
+	}
+	_, _, _, err = fc.wp.opResponse()
+	return err
+}
+
+func (fc *firebirdsqlConn) RollbackLimbo(ctx context.Context, id int64) error {
+	transHandle, err := fc.reconnect(id)
+	if err != nil {
+		return err
+	}
+	err = fc.wp.opRollback(transHandle)
+	if err != nil {
+		return err
+	}
+	_, _, _, err = fc.wp.opResponse()
+	return err
+}
@@ -0,0 +1,55 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionIdBytes(t *testing.T) {
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0x7f}, transactionIdBytes(0x7fffffff))
+	assert.Equal(t, []byte{0, 0, 0, 0x80, 0, 0, 0, 0}, transactionIdBytes(0x80000000))
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}, transactionIdBytes(0xffffffff))
+	assert.Equal(t, []byte{1, 0, 0, 0, 1, 0, 0, 0}, transactionIdBytes(0x100000001))
+}
+
+func TestParseLimboInfo(t *testing.T) {
+	ids, err := parseLimboInfo([]byte{
+		isc_info_limbo, 4, 0, 0x39, 0x30, 0, 0,
+		isc_info_limbo, 8, 0, 1, 0, 0, 0, 1, 0, 0, 0,
+		isc_info_end,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{12345, 0x100000001}, ids)
+
+	ids, err = parseLimboInfo([]byte{isc_info_end})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, err = parseLimboInfo([]byte{isc_info_limbo, 4, 0, 1, 0, 0, 0, isc_info_truncated})
+	assert.Error(t, err)
+}
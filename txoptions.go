@@ -0,0 +1,243 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// ReadCommittedMode is how a READ COMMITTED transaction reads the records
+// changed by other transactions.
+type ReadCommittedMode int
+
+const (
+	// RecordVersion reads the last committed version of a record (isc_tpb_rec_version).
+	RecordVersion ReadCommittedMode = iota
+	// NoRecordVersion waits for, or fails on, uncommitted versions of a record
+	// (isc_tpb_no_rec_version).
+	NoRecordVersion
+	// ReadConsistency reads each statement from a stable snapshot, Firebird 4 or later
+	// (isc_tpb_read_consistency).
+	ReadConsistency
+)
+
+// LockMode is how a reserved table is shared with other transactions.
+type LockMode int
+
+const (
+	// LockShared lets other transactions read and write the table.
+	LockShared LockMode = iota
+	// LockProtected lets other transactions only read the table.
+	LockProtected
+	// LockExclusive keeps other transactions off the table.
+	LockExclusive
+)
+
+// TableReservation reserves a table when a transaction starts.
+type TableReservation struct {
+	// Table is the name of the table as in SQL: it is upper-cased unless it is quoted
+	// like "foo".
+	Table string
+	Mode  LockMode
+	// Write reserves the table for writing (isc_tpb_lock_write), otherwise for reading
+	// (isc_tpb_lock_read).
+	Write bool
+}
+
+// storedTableName returns the name of table as it is stored in the metadata: the name
+// in the quotes of a quoted identifier, and an unquoted name in upper case.
+func storedTableName(table string) string {
+	if len(table) >= 2 && table[0] == '"' && table[len(table)-1] == '"' {
+		return strings.ReplaceAll(table[1:len(table)-1], `""`, `"`)
+	}
+	return strings.ToUpper(table)
+}
+
+// TxOptions holds the options of a transaction, from which its transaction parameter
+// block is built.
+//
+// TxOptions is used by BeginTransaction, and by sql.DB.BeginTx through a context
+// made by WithTxOptions.
+//
+// sql.LevelReadUncommitted, sql.LevelReadCommitted and sql.LevelWriteCommitted are READ
+// COMMITTED, sql.LevelRepeatableRead and sql.LevelSnapshot are SNAPSHOT (concurrency), and
+// sql.LevelSerializable is SNAPSHOT TABLE STABILITY (consistency).
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// ReadCommitted is the mode of a READ COMMITTED transaction.
+	ReadCommitted ReadCommittedMode
+	// NoWait makes a lock conflict fail at once instead of waiting for the other transaction.
+	NoWait bool
+	// LockTimeout is the seconds to wait on a lock conflict, 0 waits until the other
+	// transaction ends.
+	LockTimeout int
+	// Reservations are the tables reserved when the transaction starts.
+	Reservations []TableReservation
+	// NoAutoUndo does not keep the undo log of the transaction.
+	NoAutoUndo bool
+	// IgnoreLimbo ignores the records made by transactions in limbo.
+	IgnoreLimbo bool
+	// AutoCommit makes the server commit each statement of the transaction.
+	AutoCommit bool
+	// AtSnapshotNumber starts a SNAPSHOT transaction which sees the database as of the
+	// snapshot number of another transaction, Firebird 4 or later.
+	AtSnapshotNumber int64
+}
+
+// ErrIsolationLevelNotSupported is returned for sql.LevelLinearizable, which Firebird
+// can not emulate, and for unknown isolation levels.
+var ErrIsolationLevelNotSupported = errors.New("This isolation level is not supported")
+
+// ErrSnapshotNumberIsolation is returned when AtSnapshotNumber is set for an isolation
+// level other than SNAPSHOT.
+var ErrSnapshotNumberIsolation = errors.New("AtSnapshotNumber needs the SNAPSHOT isolation level")
+
+// ErrLockTimeoutNoWait is returned when both NoWait and LockTimeout are set.
+var ErrLockTimeoutNoWait = errors.New("LockTimeout can not be used with NoWait")
+
+type txOptionsKey struct{}
+
+// WithTxOptions returns a context with which sql.DB.BeginTx and sql.Conn.BeginTx start
+// transactions with opts. The isolation level and read only option given to BeginTx are
+// used when opts does not set them, then the isolation level of the DSN.
+//
+//	tx, err := db.BeginTx(firebirdsql.WithTxOptions(ctx, firebirdsql.TxOptions{NoWait: true}), nil)
+func WithTxOptions(ctx context.Context, opts TxOptions) context.Context {
+	return context.WithValue(ctx, txOptionsKey{}, opts)
+}
+
+// txOptionsOf returns the TxOptions of ctx, or the TxOptions of the DSN, merged with opts
+// of BeginTx.
+func (fc *firebirdsqlConn) txOptionsOf(ctx context.Context, opts driver.TxOptions) TxOptions {
+	txOpts, ok := ctx.Value(txOptionsKey{}).(TxOptions)
+	if !ok {
+		txOpts = fc.txOptions
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		if !ok || txOpts.Isolation == sql.LevelDefault {
+			txOpts.Isolation = sql.IsolationLevel(opts.Isolation)
+		}
+	} else if txOpts.Isolation == sql.LevelDefault && txOpts.AtSnapshotNumber == 0 {
+		txOpts.Isolation = fc.txOptions.Isolation
+	}
+	txOpts.ReadOnly = txOpts.ReadOnly || opts.ReadOnly
+	return txOpts
+}
+
+// tpb returns the transaction parameter block of opts.
+func (opts TxOptions) tpb() ([]byte, error) {
+	if opts.AtSnapshotNumber != 0 {
+		switch opts.Isolation {
+		case sql.LevelDefault:
+			opts.Isolation = sql.LevelSnapshot
+		case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		default:
+			return nil, ErrSnapshotNumberIsolation
+		}
+	}
+	tpb := []byte{byte(isc_tpb_version3)}
+	if opts.ReadOnly {
+		tpb = append(tpb, byte(isc_tpb_read))
+	} else {
+		tpb = append(tpb, byte(isc_tpb_write))
+	}
+	if opts.NoWait {
+		if opts.LockTimeout != 0 {
+			return nil, ErrLockTimeoutNoWait
+		}
+		tpb = append(tpb, byte(isc_tpb_nowait))
+	} else {
+		tpb = append(tpb, byte(isc_tpb_wait))
+		if opts.LockTimeout > 0 {
+			tpb = append(tpb, byte(isc_tpb_lock_timeout), 4)
+			tpb = append(tpb, int32_to_bytes(int32(opts.LockTimeout))...)
+		}
+	}
+
+	switch opts.Isolation {
+	case sql.LevelDefault, sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelWriteCommitted:
+		tpb = append(tpb, byte(isc_tpb_read_committed))
+		switch opts.ReadCommitted {
+		case RecordVersion:
+			tpb = append(tpb, byte(isc_tpb_rec_version))
+		case NoRecordVersion:
+			tpb = append(tpb, byte(isc_tpb_no_rec_version))
+		case ReadConsistency:
+			tpb = append(tpb, byte(isc_tpb_read_consistency))
+		default:
+			return nil, errors.New("Unknown read committed mode")
+		}
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		tpb = append(tpb, byte(isc_tpb_concurrency))
+		if opts.AtSnapshotNumber != 0 {
+			tpb = append(tpb, byte(isc_tpb_at_snapshot_number), 8)
+			tpb = append(tpb, int32_to_bytes(int32(opts.AtSnapshotNumber))...)
+			tpb = append(tpb, int32_to_bytes(int32(opts.AtSnapshotNumber>>32))...)
+		}
+	case sql.LevelSerializable:
+		tpb = append(tpb, byte(isc_tpb_consistency))
+	default:
+		return nil, ErrIsolationLevelNotSupported
+	}
+
+	for _, r := range opts.Reservations {
+		table := storedTableName(r.Table)
+		if len(table) == 0 || len(table) > 255 {
+			return nil, errors.New("Invalid table name to reserve")
+		}
+		if r.Write {
+			tpb = append(tpb, byte(isc_tpb_lock_write))
+		} else {
+			tpb = append(tpb, byte(isc_tpb_lock_read))
+		}
+		tpb = append(tpb, byte(len(table)))
+		tpb = append(tpb, table...)
+		switch r.Mode {
+		case LockShared:
+			tpb = append(tpb, byte(isc_tpb_shared))
+		case LockProtected:
+			tpb = append(tpb, byte(isc_tpb_protected))
+		case LockExclusive:
+			tpb = append(tpb, byte(isc_tpb_exclusive))
+		default:
+			return nil, errors.New("Unknown table lock mode")
+		}
+	}
+
+	if opts.NoAutoUndo {
+		tpb = append(tpb, byte(isc_tpb_no_auto_undo))
+	}
+	if opts.IgnoreLimbo {
+		tpb = append(tpb, byte(isc_tpb_ignore_limbo))
+	}
+	if opts.AutoCommit {
+		tpb = append(tpb, byte(isc_tpb_autocommit))
+	}
+	return tpb, nil
+}
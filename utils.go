@@ -27,9 +27,13 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/binary"
+	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 func str_to_bytes(s string) []byte {
@@ -70,6 +74,31 @@ func bint32_to_bytes(i32 int32) []byte {
 	return bs
 }
 
+var (
+	minInt128 = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))
+	maxInt128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+)
+
+func bint128_to_bytes(n *big.Int) ([]byte, error) {
+	if n.Cmp(minInt128) < 0 || n.Cmp(maxInt128) > 0 {
+		return nil, fmt.Errorf("Value %v overflows INT128", n)
+	}
+	v := new(big.Int).Set(n)
+	if v.Sign() < 0 { // two's complement
+		v.Add(v, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	return v.FillBytes(make([]byte, 16)), nil
+}
+
+// bytes_to_bint128 converts big-endian two's complement bytes to big.Int
+func bytes_to_bint128(b []byte) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return v
+}
+
 func int16_to_bytes(i16 int16) []byte {
 	bs := []byte{
 		byte(i16 & 0xFF),
@@ -89,6 +118,38 @@ func bytes_to_int32(b []byte) int32 {
 	return int32(binary.LittleEndian.Uint32(b))
 }
 
+// appendSegments appends the data of blob segments, each prefixed with its little endian length.
+func appendSegments(blob []byte, buf []byte) []byte {
+	for len(buf) >= 2 {
+		ln := int(binary.LittleEndian.Uint16(buf[0:2]))
+		if ln+2 > len(buf) {
+			ln = len(buf) - 2
+		}
+		blob = append(blob, buf[2:ln+2]...)
+		buf = buf[ln+2:]
+	}
+	return blob
+}
+
+// parseInfo returns the integer value of the item in the response of an info request.
+func parseInfo(buf []byte, item byte) (int64, error) {
+	for i := 0; i+3 <= len(buf) && buf[i] != isc_info_end; {
+		ln := int(bytes_to_int16(buf[i+1 : i+3]))
+		if i+3+ln > len(buf) {
+			break
+		}
+		if buf[i] == item {
+			var v int64
+			for j := ln - 1; j >= 0; j-- {
+				v = v<<8 | int64(buf[i+3+j])
+			}
+			return v, nil
+		}
+		i += 3 + ln
+	}
+	return 0, fmt.Errorf("Info item %d not found", item)
+}
+
 func bytes_to_bint16(b []byte) int16 {
 	return int16(binary.BigEndian.Uint16(b))
 }
@@ -178,6 +239,60 @@ func _int32ToBlr(i32 int32) ([]byte, []byte) {
 	return blr, v
 }
 
+func _scaledInt32ToBlr(i32 int32, scale int) ([]byte, []byte) {
+	v := bint32_to_bytes(i32)
+	blr := []byte{8, byte(scale)}
+
+	return blr, v
+}
+
+func _scaledInt64ToBlr(i64 int64, scale int) ([]byte, []byte) {
+	v := bint64_to_bytes(i64)
+	blr := []byte{16, byte(scale)}
+
+	return blr, v
+}
+
+func _int128ToBlr(n *big.Int, scale int) ([]byte, []byte, error) {
+	v, err := bint128_to_bytes(n)
+	blr := []byte{26, byte(scale)}
+
+	return blr, v, err
+}
+
+func _decimalToBlr(d decimal.Decimal) ([]byte, []byte, error) {
+	coef := d.Coefficient()
+	exp := d.Exponent()
+	if exp > 0 {
+		coef.Mul(coef, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+		exp = 0
+	}
+	if exp < math.MinInt8 {
+		return nil, nil, fmt.Errorf("Value %v has too many decimal places", d)
+	}
+	if coef.IsInt64() {
+		blr, v := _scaledInt64ToBlr(coef.Int64(), int(exp))
+		return blr, v, nil
+	}
+	return _int128ToBlr(coef, int(exp))
+}
+
+func _decFloatToBlr(d DecFloat, isDecimal64 bool) ([]byte, []byte, error) {
+	if isDecimal64 {
+		v, err := decFloatToBytes(d, 8)
+		return []byte{24}, v, err
+	}
+	v, err := decFloatToBytes(d, 16)
+	return []byte{25}, v, err
+}
+
+func _float32ToBlr(v float32) ([]byte, []byte) {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, v)
+	blr := []byte{10}
+	return blr, buf.Bytes()
+}
+
 func _float64ToBlr(v float64) ([]byte, []byte) {
 	buf := new(bytes.Buffer)
 	binary.Write(buf, binary.BigEndian, v)
@@ -234,6 +349,57 @@ func _timestampToBlr(t time.Time) ([]byte, []byte) {
 	return blr, v
 }
 
+// the time zone id of a fixed offset is the offset minutes + 1439
+const timezoneOffsetBase = 24*60 - 1
+
+func timezoneIDOf(t time.Time) (int, error) {
+	if id, ok := getTimezoneIDByName(t.Location().String()); ok {
+		return id, nil
+	}
+	_, offset := t.Zone()
+	if offset%60 != 0 || offset/60 < -timezoneOffsetBase || offset/60 > timezoneOffsetBase {
+		return 0, fmt.Errorf("Time zone offset %ds can not be stored", offset)
+	}
+	return offset/60 + timezoneOffsetBase, nil
+}
+
+// timeTzOnBaseDate returns the time of day on 2020-01-01, the date on which Firebird
+// resolves the offset of a region of TIME WITH TIME ZONE.
+func timeTzOnBaseDate(h, m, s, n int, loc *time.Location) time.Time {
+	return time.Date(2020, time.January, 1, h, m, s, n, loc)
+}
+
+func _timeTzToBlr(t time.Time) ([]byte, []byte, error) {
+	id, err := timezoneIDOf(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	u := timeTzOnBaseDate(t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).UTC()
+	v := bytes.Join([][]byte{
+		_convert_time(u),
+		bint32_to_bytes(int32(int16(id))),
+	}, nil)
+
+	blr := []byte{28}
+	return blr, v, nil
+}
+
+func _timestampTzToBlr(t time.Time) ([]byte, []byte, error) {
+	id, err := timezoneIDOf(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	u := t.UTC()
+	v := bytes.Join([][]byte{
+		_convert_date(u),
+		_convert_time(u),
+		bint32_to_bytes(int32(int16(id))),
+	}, nil)
+
+	blr := []byte{29}
+	return blr, v, nil
+}
+
 func convertToBool(s string, defaultValue bool) bool {
 	v, err := strconv.ParseBool(s)
 	if err != nil {
@@ -24,7 +24,13 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package firebirdsql
 
 import (
+	"math/big"
 	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDSNParse(t *testing.T) {
@@ -89,4 +95,104 @@ func TestDSNParse(t *testing.T) {
 		t.Fatalf("Error Not occured")
 	}
 
+	dsn, err := parseDSN("user:password@localhost/dbname?dialect=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn.options["dialect"] != "1" {
+		t.Errorf("parse DSN fail:dialect(%s != 1)", dsn.options["dialect"])
+	}
+	_, err = parseDSN("user:password@localhost/dbname?dialect=4")
+	if err != ErrDsnInvalidDialect {
+		t.Fatalf("Incorrect error: %v", err)
+	}
+
+}
+
+func TestInitConn(t *testing.T) {
+	dsn, err := parseDSN("user:password@localhost/dbname?column_name_to_lower=true&char_trim=true&blob_streaming=true&statement_cache_size=8&tx_isolation=read_consistency&server_autocommit=true")
+	require.NoError(t, err)
+	fc, err := initConn(&wireProtocol{}, dsn, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, fc.columnNameToLower)
+	assert.True(t, fc.charTrim)
+	assert.True(t, fc.blobStreaming)
+	assert.NotNil(t, fc.stmtCache)
+	assert.True(t, fc.serverAutocommit)
+	assert.Equal(t, fc.txOptions.Isolation, fc.tx.opts.Isolation)
+	assert.True(t, fc.tx.opts.AutoCommit)
+	assert.True(t, fc.tx.needBegin)
+}
+
+func TestInt128Codec(t *testing.T) {
+	for _, s := range []string{
+		"0", "1", "-1", "255", "-256",
+		"170141183460469231731687303715884105727",
+		"-170141183460469231731687303715884105728",
+	} {
+		n, _ := new(big.Int).SetString(s, 10)
+		b, err := bint128_to_bytes(n)
+		require.NoError(t, err)
+		require.Len(t, b, 16)
+		assert.Equal(t, s, bytes_to_bint128(b).String())
+	}
+
+	n, _ := new(big.Int).SetString("-123456789012345678901234567", 10)
+	raw, _ := bint128_to_bytes(n)
+	orig := append([]byte{}, raw...)
+	x := &xSQLVAR{sqltype: SQL_TYPE_INT128, sqlscale: -10}
+	v, err := x.value(raw, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "-12345678901234567.8901234567", v.(decimal.Decimal).String())
+	assert.Equal(t, orig, raw, "raw value is not modified")
+	assert.Equal(t, "Decimal", x.scantype().Name())
+}
+
+func TestTimezoneByID(t *testing.T) {
+	loc, err := getTimezoneByID(65535)
+	require.NoError(t, err)
+	assert.Equal(t, "GMT", loc.String())
+
+	loc, err = getTimezoneByID(1439 + 330)
+	require.NoError(t, err)
+	assert.Equal(t, "+05:30", loc.String())
+	_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, 330*60, offset)
+
+	loc, err = getTimezoneByID(0)
+	require.NoError(t, err)
+	assert.Equal(t, "-23:59", loc.String())
+
+	_, err = getTimezoneByID(30000)
+	assert.EqualError(t, err, "Unknown time zone id 30000")
+
+	// zones added by Firebird 5
+	for id, name := range map[int]string{64901: "Pacific/Kanton", 64900: "Europe/Kyiv", 64899: "America/Ciudad_Juarez"} {
+		assert.Equal(t, name, getTimezoneNameByID(id))
+		got, ok := getTimezoneIDByName(name)
+		assert.True(t, ok)
+		assert.Equal(t, id, got)
+	}
+
+	// an unknown id is an error, not a panic
+	x := &xSQLVAR{sqltype: SQL_TYPE_TIMESTAMP_TZ}
+	raw := []byte{0, 0, 0xe4, 0x63, 0, 0, 0, 0, 0, 0, 0x75, 0x30}
+	_, err = x.value(raw, "", "")
+	assert.EqualError(t, err, "Unknown time zone id 30000")
+	_, err = x.value(raw[:10], "", "")
+	assert.EqualError(t, err, "Invalid TIMESTAMP WITH TIME ZONE length 10")
+
+	// fixed offsets round trip
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", -(3*60+30)*60))
+	_, v, err := _timestampTzToBlr(ts)
+	require.NoError(t, err)
+	decoded, err := x.value(v, "", "")
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(decoded.(time.Time)))
+	assert.Equal(t, "-03:30", decoded.(time.Time).Location().String())
+
+	_, err = parseDSN("user:password@localhost/dbname?timezone=Nowhere/Unknown")
+	assert.Error(t, err)
+	_, err = parseDSN("user:password@localhost/dbname?timezone=%2B09:00")
+	assert.NoError(t, err)
 }
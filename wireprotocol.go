@@ -33,6 +33,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/big"
 	"net"
 	"os"
@@ -41,6 +43,7 @@ import (
 	"time"
 
 	"github.com/kardianos/osext"
+	"github.com/shopspring/decimal"
 	"gitlab.com/nyarla/go-crypt"
 	"golang.org/x/crypto/chacha20"
 	//"unsafe"
@@ -71,6 +74,12 @@ func _INFO_SQL_SELECT_DESCRIBE_VARS() []byte {
 	}
 }
 
+func _INFO_SQL_BIND_DESCRIBE_VARS() []byte {
+	b := _INFO_SQL_SELECT_DESCRIBE_VARS()
+	b[0] = isc_info_sql_bind
+	return b
+}
+
 type wireChannel struct {
 	conn           net.Conn
 	reader         *bufio.Reader
@@ -174,6 +183,7 @@ type wireProtocol struct {
 
 	charset        string
 	charsetByteLen int
+	dialect        int
 
 	// Time Zone
 	timezone string
@@ -193,6 +203,7 @@ func newWireProtocol(addr string, timezone string, charset string) (*wireProtoco
 	p.timezone = timezone
 	p.charset = charset
 	p.charsetLen()
+	p.dialect = 3
 
 	return p, err
 }
@@ -202,9 +213,9 @@ func (p *wireProtocol) charsetLen() {
 	// all ISO8859_X and WIN125X are 1 byte character length, so omit here
 	// only add charset that character length is > 1
 	switch p.charset {
-	case "UNICODE_FSS", "UTF8":
+	case "UNICODE_FSS", "UTF8", "GB18030":
 		p.charsetByteLen = 4
-	case "BIG_5", "SJIS_0208", "KSC_5601", "EUCJ_0208", "GB_2312", "KOI8R", "KOI8U":
+	case "BIG_5", "SJIS_0208", "KSC_5601", "EUCJ_0208", "GB_2312", "GBK", "KOI8R", "KOI8U":
 		p.charsetByteLen = 2
 	default:
 		p.charsetByteLen = 1
@@ -407,7 +418,11 @@ func (p *wireProtocol) _parse_op_response() (int32, []byte, []byte, error) {
 
 	gds_code_list, sql_code, message, err := p._parse_status_vector()
 	if gds_code_list.Len() > 0 || sql_code != 0 {
-		err = errors.New(message)
+		fbErr := &FbError{SQLCode: sql_code, Message: message}
+		for e := gds_code_list.Front(); e != nil; e = e.Next() {
+			fbErr.GDSCodes = append(fbErr.GDSCodes, e.Value.(int))
+		}
+		err = fbErr
 	}
 
 	return h, oid, buf, err
@@ -569,12 +584,19 @@ func (p *wireProtocol) _parse_connect_response(user string, password string, opt
 	return
 }
 
-func (p *wireProtocol) _parse_select_items(buf []byte, xsqlda []xSQLVAR) (int, error) {
-	var err error
+// _parse_select_items parses describe items into xsqlda.
+// It returns the index to request next when the buffer was truncated (or -1),
+// and the number of bytes consumed.
+func (p *wireProtocol) _parse_select_items(buf []byte, xsqlda []xSQLVAR) (int, int, error) {
 	var ln int
 	index := 0
 	i := 0
-	for item := int(buf[i]); item != isc_info_end; item = int(buf[i]) {
+	for i < len(buf) {
+		item := int(buf[i])
+		switch item {
+		case isc_info_end, isc_info_sql_select, isc_info_sql_bind:
+			return -1, i, nil // no more info
+		}
 		i++
 		switch item {
 		case isc_info_sql_sqlda_seq:
@@ -633,22 +655,65 @@ func (p *wireProtocol) _parse_select_items(buf []byte, xsqlda []xSQLVAR) (int, e
 			xsqlda[index-1].aliasname = bytes_to_str(buf[i : i+ln])
 			i += ln
 		case isc_info_truncated:
-			return index, err // return next index
+			return index, i, nil // return next index
 		case isc_info_sql_describe_end:
 			/* NOTHING */
 		default:
-			err = errors.New(fmt.Sprintf("Invalid item [%02x] ! i=%d", buf[i], i))
-			break
+			return -1, i, errors.New(fmt.Sprintf("Invalid item [%02x] ! i=%d", item, i-1))
+		}
+	}
+	return -1, i, nil
+}
+
+// parseDescribeVars parses a select or bind describe block starting at buf[0].
+// The rest of the block is requested by op_info_sql when the buffer was truncated.
+// It returns the variables and the number of bytes consumed, or -1 when truncated.
+func (p *wireProtocol) parseDescribeVars(buf []byte, stmtHandle int32, describeVars []byte) ([]xSQLVAR, int, error) {
+	i := 2
+	ln := int(bytes_to_int16(buf[i : i+2]))
+	i += 2
+	col_len := int(bytes_to_int32(buf[i : i+ln]))
+	i += ln
+	xsqlda := make([]xSQLVAR, col_len)
+	next_index, n, err := p._parse_select_items(buf[i:], xsqlda)
+	if err != nil {
+		return nil, 0, err
+	}
+	if next_index < 0 {
+		return xsqlda, i + n, nil
+	}
+	for next_index > 0 { // more describe vars
+		err = p.opInfoSql(stmtHandle,
+			bytes.Join([][]byte{
+				[]byte{isc_info_sql_sqlda_start, 2},
+				int16_to_bytes(int16(next_index)),
+				describeVars,
+			}, nil))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		_, _, buf, err = p.opResponse()
+		if err != nil {
+			return nil, 0, err
+		}
+		// buf[:2] == []byte{describe kind, isc_info_sql_describe_vars}
+		ln = int(bytes_to_int16(buf[2:4]))
+		// bytes_to_int(buf[4:4+l]) == col_len
+		next_index, _, err = p._parse_select_items(buf[4+ln:], xsqlda)
+		if err != nil {
+			return nil, 0, err
 		}
 	}
-	return -1, err // no more info
+	return xsqlda, -1, nil
 }
 
-func (p *wireProtocol) parse_xsqlda(buf []byte, stmtHandle int32) (int32, []xSQLVAR, error) {
-	var ln, col_len, next_index int
+func (p *wireProtocol) parse_xsqlda(buf []byte, stmtHandle int32) (int32, []xSQLVAR, []xSQLVAR, error) {
+	var ln, n int
 	var err error
 	var stmt_type int32
-	var xsqlda []xSQLVAR
+	var xsqlda, paramXsqlda []xSQLVAR
+	var hasBind bool
 	i := 0
 
 	for i < len(buf) {
@@ -659,32 +724,39 @@ func (p *wireProtocol) parse_xsqlda(buf []byte, stmtHandle int32) (int32, []xSQL
 			stmt_type = int32(bytes_to_int32(buf[i : i+ln]))
 			i += ln
 		} else if buf[i] == byte(isc_info_sql_select) && buf[i+1] == byte(isc_info_sql_describe_vars) {
-			i += 2
-			ln = int(bytes_to_int16(buf[i : i+2]))
-			i += 2
-			col_len = int(bytes_to_int32(buf[i : i+ln]))
-			xsqlda = make([]xSQLVAR, col_len)
-			next_index, err = p._parse_select_items(buf[i+ln:], xsqlda)
-			for next_index > 0 { // more describe vars
-				p.opInfoSql(stmtHandle,
-					bytes.Join([][]byte{
-						[]byte{isc_info_sql_sqlda_start, 2},
-						int16_to_bytes(int16(next_index)),
-						_INFO_SQL_SELECT_DESCRIBE_VARS(),
-					}, nil))
-
-				_, _, buf, err = p.opResponse()
-				// buf[:2] == []byte{0x04,0x07}
-				ln = int(bytes_to_int16(buf[2:4]))
-				// bytes_to_int(buf[4:4+l]) == col_len
-				next_index, err = p._parse_select_items(buf[4+ln:], xsqlda)
+			xsqlda, n, err = p.parseDescribeVars(buf[i:], stmtHandle, _INFO_SQL_SELECT_DESCRIBE_VARS())
+			if err != nil || n < 0 {
+				break
+			}
+			i += n
+		} else if buf[i] == byte(isc_info_sql_bind) && buf[i+1] == byte(isc_info_sql_describe_vars) {
+			paramXsqlda, n, err = p.parseDescribeVars(buf[i:], stmtHandle, _INFO_SQL_BIND_DESCRIBE_VARS())
+			hasBind = true
+			if err != nil || n < 0 {
+				break
 			}
+			i += n
 		} else {
 			break
 		}
 	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
 
-	return stmt_type, xsqlda, err
+	if !hasBind { // the bind describe block did not fit in the prepare response
+		err = p.opInfoSql(stmtHandle, _INFO_SQL_BIND_DESCRIBE_VARS())
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		_, _, buf, err = p.opResponse()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		paramXsqlda, _, err = p.parseDescribeVars(buf, stmtHandle, _INFO_SQL_BIND_DESCRIBE_VARS())
+	}
+
+	return stmt_type, xsqlda, paramXsqlda, err
 }
 
 func (p *wireProtocol) getBlobSegments(blobId []byte, transHandle int32) ([]byte, error) {
@@ -703,12 +775,10 @@ func (p *wireProtocol) getBlobSegments(blobId []byte, transHandle int32) ([]byte
 	for more_data != 2 {
 		p.opGetSegment(blobHandle)
 		more_data, _, rbuf, err = p.opResponse()
-		buf := rbuf
-		for len(buf) > 0 {
-			ln := int(bytes_to_int16(buf[0:2]))
-			blob = append(blob, buf[2:ln+2]...)
-			buf = buf[ln+2:]
+		if err != nil {
+			break
 		}
+		blob = appendSegments(blob, rbuf)
 	}
 
 	p.opCloseBlob(blobHandle)
@@ -750,29 +820,52 @@ func (p *wireProtocol) opConnect(dbName string, user string, password string, op
 	return err
 }
 
-func (p *wireProtocol) opCreate(dbName string, user string, password string, role string) error {
+func (p *wireProtocol) opCreate(dbName string, user string, password string, role string, opts CreateOptions) error {
 	p.debugPrint("opCreate")
-	var page_size int32
-	page_size = 4096
+	charset := opts.Charset
+	if charset == "" {
+		charset = p.charset
+	}
+	if opts.Dialect == 0 {
+		opts.Dialect = p.dialect
+	}
 
+	dbCharsetBytes := bytes.NewBufferString(charset).Bytes()
 	encode := bytes.NewBufferString(p.charset).Bytes()
 	userBytes := bytes.NewBufferString(strings.ToUpper(user)).Bytes()
 	passwordBytes := bytes.NewBufferString(password).Bytes()
 	roleBytes := []byte(role)
+	var forcedWrites, overwrite int32
+	if !opts.NoForcedWrites {
+		forcedWrites = 1
+	}
+	if opts.Overwrite {
+		overwrite = 1
+	}
 	dpb := bytes.Join([][]byte{
 		[]byte{isc_dpb_version1},
-		[]byte{isc_dpb_set_db_charset, byte(len(encode))}, encode,
+		[]byte{isc_dpb_set_db_charset, byte(len(dbCharsetBytes))}, dbCharsetBytes,
 		[]byte{isc_dpb_lc_ctype, byte(len(encode))}, encode,
 		[]byte{isc_dpb_user_name, byte(len(userBytes))}, userBytes,
 		[]byte{isc_dpb_password, byte(len(passwordBytes))}, passwordBytes,
 		[]byte{isc_dpb_sql_role_name, byte(len(roleBytes))}, roleBytes,
-		[]byte{isc_dpb_sql_dialect, 4}, int32_to_bytes(3),
-		[]byte{isc_dpb_force_write, 4}, bint32_to_bytes(1),
-		[]byte{isc_dpb_overwrite, 4}, bint32_to_bytes(1),
-		[]byte{isc_dpb_page_size, 4}, int32_to_bytes(page_size),
+		[]byte{isc_dpb_sql_dialect, 4}, int32_to_bytes(int32(opts.Dialect)),
+		[]byte{isc_dpb_force_write, 4}, int32_to_bytes(forcedWrites),
+		[]byte{isc_dpb_overwrite, 4}, int32_to_bytes(overwrite),
+		[]byte{isc_dpb_page_size, 4}, int32_to_bytes(int32(opts.PageSize)),
 		[]byte{isc_dpb_utf8_filename, 1, 1},
 	}, nil)
 
+	if opts.SweepInterval != 0 {
+		sweepInterval := int32(opts.SweepInterval)
+		if sweepInterval < 0 {
+			sweepInterval = 0 // disable automatic sweep
+		}
+		dpb = bytes.Join([][]byte{
+			dpb,
+			[]byte{isc_dpb_sweep_interval, 4}, int32_to_bytes(sweepInterval)}, nil)
+	}
+
 	if p.authData != nil {
 		specificAuthData := bytes.NewBufferString(hex.EncodeToString(p.authData)).Bytes()
 		dpb = bytes.Join([][]byte{
@@ -815,7 +908,7 @@ func (p *wireProtocol) opAttach(dbName string, user string, password string, rol
 
 	dpb := bytes.Join([][]byte{
 		[]byte{isc_dpb_version1},
-		[]byte{isc_dpb_sql_dialect, 4}, int32_to_bytes(3),
+		[]byte{isc_dpb_sql_dialect, 4}, int32_to_bytes(int32(p.dialect)),
 		[]byte{isc_dpb_lc_ctype, byte(len(encode))}, encode,
 		[]byte{isc_dpb_user_name, byte(len(userBytes))}, userBytes,
 		[]byte{isc_dpb_password, byte(len(passwordBytes))}, passwordBytes,
@@ -923,6 +1016,24 @@ func (p *wireProtocol) opRollbackRetaining(transHandle int32) error {
 	return err
 }
 
+func (p *wireProtocol) opPrepare2(transHandle int32, description []byte) error {
+	p.debugPrint("opPrepare2():%d", transHandle)
+	p.packInt(op_prepare2)
+	p.packInt(transHandle)
+	p.packBytes(description)
+	_, err := p.sendPackets()
+	return err
+}
+
+func (p *wireProtocol) opReconnect(transactionId int64) error {
+	p.debugPrint("opReconnect():%d", transactionId)
+	p.packInt(op_reconnect)
+	p.packInt(p.dbHandle)
+	p.packBytes(transactionIdBytes(transactionId))
+	_, err := p.sendPackets()
+	return err
+}
+
 func (p *wireProtocol) opAllocateStatement() error {
 	p.debugPrint("opAllocateStatement")
 	p.packInt(op_allocate_statement)
@@ -968,11 +1079,12 @@ func (p *wireProtocol) opPrepareStatement(stmtHandle int32, transHandle int32, q
 	bs := bytes.Join([][]byte{
 		[]byte{isc_info_sql_stmt_type},
 		_INFO_SQL_SELECT_DESCRIBE_VARS(),
+		_INFO_SQL_BIND_DESCRIBE_VARS(),
 	}, nil)
 	p.packInt(op_prepare_statement)
 	p.packInt(transHandle)
 	p.packInt(stmtHandle)
-	p.packInt(3) // dialect = 3
+	p.packInt(int32(p.dialect))
 	p.packString(query)
 	p.packBytes(bs)
 	p.packInt(int32(BUFFER_LEN))
@@ -991,8 +1103,18 @@ func (p *wireProtocol) opInfoSql(stmtHandle int32, vars []byte) error {
 	return err
 }
 
-func (p *wireProtocol) opExecute(stmtHandle int32, transHandle int32, params []driver.Value) error {
+func (p *wireProtocol) opExecute(stmtHandle int32, transHandle int32, params []driver.Value, paramXsqlda []xSQLVAR) error {
 	p.debugPrint("opExecute():%d,%d,%v", transHandle, stmtHandle, params)
+	var blr, values []byte
+	var err error
+	if len(params) != 0 {
+		// blobs are created before the op_execute packet is built
+		blr, values, err = p.paramsToBlr(transHandle, params, paramXsqlda, p.protocolVersion)
+		if err != nil {
+			return err
+		}
+	}
+
 	p.packInt(op_execute)
 	p.packInt(stmtHandle)
 	p.packInt(transHandle)
@@ -1002,7 +1124,6 @@ func (p *wireProtocol) opExecute(stmtHandle int32, transHandle int32, params []d
 		p.packInt(0)
 		p.packInt(0)
 	} else {
-		blr, values := p.paramsToBlr(transHandle, params, p.protocolVersion)
 		p.packBytes(blr)
 		p.packInt(0)
 		p.packInt(1)
@@ -1012,12 +1133,22 @@ func (p *wireProtocol) opExecute(stmtHandle int32, transHandle int32, params []d
 		// statement timeout
 		p.appendBytes(bint32_to_bytes(0))
 	}
-	_, err := p.sendPackets()
+	_, err = p.sendPackets()
 	return err
 }
 
-func (p *wireProtocol) opExecute2(stmtHandle int32, transHandle int32, params []driver.Value, outputBlr []byte) error {
+func (p *wireProtocol) opExecute2(stmtHandle int32, transHandle int32, params []driver.Value, paramXsqlda []xSQLVAR, outputBlr []byte) error {
 	p.debugPrint("opExecute2")
+	var blr, values []byte
+	var err error
+	if len(params) != 0 {
+		// blobs are created before the op_execute2 packet is built
+		blr, values, err = p.paramsToBlr(transHandle, params, paramXsqlda, p.protocolVersion)
+		if err != nil {
+			return err
+		}
+	}
+
 	p.packInt(op_execute2)
 	p.packInt(stmtHandle)
 	p.packInt(transHandle)
@@ -1027,7 +1158,6 @@ func (p *wireProtocol) opExecute2(stmtHandle int32, transHandle int32, params []
 		p.packInt(0)
 		p.packInt(0)
 	} else {
-		blr, values := p.paramsToBlr(transHandle, params, p.protocolVersion)
 		p.packBytes(blr)
 		p.packInt(0)
 		p.packInt(1)
@@ -1042,7 +1172,7 @@ func (p *wireProtocol) opExecute2(stmtHandle int32, transHandle int32, params []
 		p.appendBytes(bint32_to_bytes(0))
 	}
 
-	_, err := p.sendPackets()
+	_, err = p.sendPackets()
 	return err
 }
 
@@ -1082,6 +1212,8 @@ func (p *wireProtocol) opFetchResponse(stmtHandle int32, transHandle int32, xsql
 	status := bytes_to_bint32(b[:4])
 	count := int(bytes_to_bint32(b[4:8]))
 	rows := list.New()
+	// the first error of decoding a value is returned after the whole response is read
+	var valueErr error
 
 	for count > 0 {
 		r := make([]driver.Value, len(xsqlda))
@@ -1098,6 +1230,9 @@ func (p *wireProtocol) opFetchResponse(stmtHandle int32, transHandle int32, xsql
 				b, err = p.recvPackets(4)
 				if bytes_to_bint32(b) == 0 { // Not NULL
 					r[i], err = x.value(raw_value, p.timezone, p.charset)
+					if valueErr == nil {
+						valueErr = err
+					}
 				}
 			}
 		} else { // PROTOCOL_VERSION13
@@ -1127,6 +1262,9 @@ func (p *wireProtocol) opFetchResponse(stmtHandle int32, transHandle int32, xsql
 				}
 				raw_value, _ := p.recvPacketsAlignment(ln)
 				r[i], err = x.value(raw_value, p.timezone, p.charset)
+				if valueErr == nil {
+					valueErr = err
+				}
 			}
 		}
 
@@ -1138,6 +1276,9 @@ func (p *wireProtocol) opFetchResponse(stmtHandle int32, transHandle int32, xsql
 		count = int(bytes_to_bint32(b[8:]))
 	}
 
+	if valueErr != nil {
+		return nil, false, valueErr
+	}
 	return rows, status != 100, err
 }
 
@@ -1158,10 +1299,10 @@ func (p *wireProtocol) opOpenBlob(blobId []byte, transHandle int32) error {
 	return err
 }
 
-func (p *wireProtocol) opCreateBlob2(transHandle int32) error {
+func (p *wireProtocol) opCreateBlob2(transHandle int32, bpb []byte) error {
 	p.debugPrint("opCreateBlob2")
 	p.packInt(op_create_blob2)
-	p.packInt(0)
+	p.packBytes(bpb)
 	p.packInt(transHandle)
 	p.packInt(0)
 	p.packInt(0)
@@ -1193,18 +1334,61 @@ func (p *wireProtocol) opPutSegment(blobHandle int32, seg_data []byte) error {
 	return err
 }
 
-func (p *wireProtocol) opBatchSegments(blobHandle int32, seg_data []byte) error {
+func (p *wireProtocol) opBatchSegments(blobHandle int32, segments []byte) error {
 	p.debugPrint("opBatchSegments")
-	ln := len(seg_data)
+	ln := len(segments)
 	p.packInt(op_batch_segments)
 	p.packInt(blobHandle)
-	p.packInt(int32(ln + 2))
-	p.packInt(int32(ln + 2))
-	pad_length := ((4 - (ln + 2)) & 3)
-	padding := make([]byte, pad_length)
-	p.packBytes([]byte{byte(ln & 255), byte(ln >> 8)}) // little endian int16
-	p.packBytes(seg_data)
-	p.packBytes(padding)
+	p.packInt(int32(ln))
+	p.packBytes(segments)
+	_, err := p.sendPackets()
+	return err
+}
+
+func (p *wireProtocol) opSeekBlob(blobHandle int32, mode int32, offset int32) error {
+	p.debugPrint("opSeekBlob")
+	p.packInt(op_seek_blob)
+	p.packInt(blobHandle)
+	p.packInt(mode)
+	p.packInt(offset)
+	_, err := p.sendPackets()
+	return err
+}
+
+func (p *wireProtocol) opInfoBlob(blobHandle int32, items []byte) error {
+	p.debugPrint("opInfoBlob")
+	p.packInt(op_info_blob)
+	p.packInt(blobHandle)
+	p.packInt(0)
+	p.packBytes(items)
+	p.packInt(int32(BUFFER_LEN))
+	_, err := p.sendPackets()
+	return err
+}
+
+func (p *wireProtocol) opGetSlice(transHandle int32, arrayId []byte, sdl []byte, sliceLength int) error {
+	p.debugPrint("opGetSlice")
+	p.packInt(op_get_slice)
+	p.packInt(transHandle)
+	p.appendBytes(arrayId)
+	p.packInt(int32(sliceLength))
+	p.packBytes(sdl)
+	p.packInt(0) // parameters
+	p.packInt(0) // slice
+	_, err := p.sendPackets()
+	return err
+}
+
+func (p *wireProtocol) opPutSlice(transHandle int32, sdl []byte, sliceLength int, data []byte) error {
+	p.debugPrint("opPutSlice")
+	p.packInt(op_put_slice)
+	p.packInt(transHandle)
+	p.appendBytes(make([]byte, 8)) // new array
+	p.packInt(int32(sliceLength))
+	p.packBytes(sdl)
+	p.packInt(0) // parameters
+	p.packInt(int32(sliceLength))
+	p.appendBytes(data)
 	_, err := p.sendPackets()
 	return err
 }
@@ -1217,6 +1401,14 @@ func (p *wireProtocol) opCloseBlob(blobHandle int32) error {
 	return err
 }
 
+func (p *wireProtocol) opCancelBlob(blobHandle int32) error {
+	p.debugPrint("opCancelBlob")
+	p.packInt(op_cancel_blob)
+	p.packInt(blobHandle)
+	_, err := p.sendPackets()
+	return err
+}
+
 func (p *wireProtocol) opResponse() (int32, []byte, []byte, error) {
 	p.debugPrint("opResponse")
 	b, err := p.recvPackets(4)
@@ -1252,6 +1444,52 @@ func (p *wireProtocol) opResponse() (int32, []byte, []byte, error) {
 	return p._parse_op_response()
 }
 
+func (p *wireProtocol) opSliceResponse(element *xSQLVAR, elementLength int) ([][]byte, error) {
+	p.debugPrint("opSliceResponse")
+	b, err := p.recvPackets(4)
+	if err != nil {
+		return nil, err
+	}
+	for bytes_to_bint32(b) == op_dummy {
+		b, _ = p.recvPackets(4)
+	}
+	for bytes_to_bint32(b) == op_response && p.lazyResponseCount > 0 {
+		p.lazyResponseCount--
+		p._parse_op_response()
+		b, _ = p.recvPackets(4)
+	}
+	if bytes_to_bint32(b) != op_slice {
+		if bytes_to_bint32(b) == op_response {
+			_, _, _, err := p._parse_op_response()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, errors.New("opSliceResponse:Internal Error")
+	}
+
+	b, err = p.recvPackets(8)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([][]byte, int(bytes_to_bint32(b[4:8]))/elementLength)
+	for i := range elements {
+		ln := element.ioLength()
+		if ln < 0 {
+			b, err = p.recvPackets(4)
+			if err != nil {
+				return nil, err
+			}
+			ln = int(bytes_to_bint32(b))
+		}
+		elements[i], err = p.recvPacketsAlignment(ln)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
 func (p *wireProtocol) opSqlResponse(xsqlda []xSQLVAR) ([]driver.Value, error) {
 	p.debugPrint("opSqlResponse")
 	b, err := p.recvPackets(4)
@@ -1271,6 +1509,8 @@ func (p *wireProtocol) opSqlResponse(xsqlda []xSQLVAR) ([]driver.Value, error) {
 
 	r := make([]driver.Value, len(xsqlda))
 	var ln int
+	// the first error of decoding a value is returned after the whole response is read
+	var valueErr error
 
 	if p.protocolVersion < PROTOCOL_VERSION13 {
 		for i, x := range xsqlda {
@@ -1284,6 +1524,9 @@ func (p *wireProtocol) opSqlResponse(xsqlda []xSQLVAR) ([]driver.Value, error) {
 			b, err = p.recvPackets(4)
 			if bytes_to_bint32(b) == 0 { // Not NULL
 				r[i], err = x.value(raw_value, p.timezone, p.charset)
+				if valueErr == nil {
+					valueErr = err
+				}
 			}
 		}
 	} else { // PROTOCOL_VERSION13
@@ -1312,49 +1555,328 @@ func (p *wireProtocol) opSqlResponse(xsqlda []xSQLVAR) ([]driver.Value, error) {
 			}
 			raw_value, _ := p.recvPacketsAlignment(ln)
 			r[i], err = x.value(raw_value, p.timezone, p.charset)
+			if valueErr == nil {
+				valueErr = err
+			}
 		}
 	}
 
+	if valueErr != nil {
+		return nil, valueErr
+	}
 	return r, err
 }
 
 func (p *wireProtocol) createBlob(value []byte, transHandle int32) ([]byte, error) {
-	buf := p.suspendBuffer()
-	p.opCreateBlob2(transHandle)
-	blobHandle, blobId, _, err := p.opResponse()
+	w, err := newBlobWriter(p, transHandle, false)
 	if err != nil {
-		p.resumeBuffer(buf)
-		return blobId, err
+		return nil, err
+	}
+	if _, err = w.Write(value); err != nil {
+		w.cancel()
+		return nil, err
 	}
+	if err = w.Close(); err != nil {
+		w.cancel()
+		return nil, err
+	}
+	return w.blobId, nil
+}
 
-	i := 0
-	for i < len(value) {
-		end := i + BLOB_SEGMENT_SIZE
-		if end > len(value) {
-			end = len(value)
+func (p *wireProtocol) getSlice(transHandle int32, arrayId []byte, desc *arrayDesc) ([][]byte, error) {
+	suspendBuf := p.suspendBuffer()
+	defer p.resumeBuffer(suspendBuf)
+	if err := p.opGetSlice(transHandle, arrayId, desc.sdl(), desc.sliceLength()); err != nil {
+		return nil, err
+	}
+	return p.opSliceResponse(&desc.element, desc.sliceLength()/desc.count())
+}
+
+func (p *wireProtocol) putSlice(transHandle int32, desc *arrayDesc, data []byte) ([]byte, error) {
+	suspendBuf := p.suspendBuffer()
+	defer p.resumeBuffer(suspendBuf)
+	if err := p.opPutSlice(transHandle, desc.sdl(), desc.sliceLength(), data); err != nil {
+		return nil, err
+	}
+	_, arrayId, _, err := p.opResponse()
+	return arrayId, err
+}
+
+func (p *wireProtocol) createBlobFromReader(r io.Reader, transHandle int32) ([]byte, error) {
+	w, err := newBlobWriter(p, transHandle, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.ReadFrom(r); err != nil {
+		w.cancel()
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		w.cancel()
+		return nil, err
+	}
+	return w.blobId, nil
+}
+
+// paramToDecimal converts a numeric parameter to decimal.Decimal.
+func paramToDecimal(param driver.Value) (decimal.Decimal, bool) {
+	switch f := param.(type) {
+	case int:
+		return decimal.NewFromInt(int64(f)), true
+	case int16:
+		return decimal.NewFromInt(int64(f)), true
+	case int32:
+		return decimal.NewFromInt(int64(f)), true
+	case int64:
+		return decimal.NewFromInt(f), true
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(f), 0), true
+	case float32:
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return decimal.Zero, false
+		}
+		return decimal.NewFromFloat32(f), true
+	case decimal.Decimal:
+		return f, true
+	case *big.Int:
+		return decimal.NewFromBigInt(f, 0), true
+	case float64:
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return decimal.Zero, false
 		}
-		p.opPutSegment(blobHandle, value[i:end])
-		_, _, _, err := p.opResponse()
+		return decimal.NewFromFloat(f), true
+	case bool:
+		if f {
+			return decimal.NewFromInt(1), true
+		}
+		return decimal.Zero, true
+	case string:
+		d, err := decimal.NewFromString(strings.TrimSpace(f))
+		return d, err == nil
+	}
+	return decimal.Zero, false
+}
+
+// scaledIntToBlr converts a parameter to the exact scaled integer of a SMALLINT, INTEGER, BIGINT or INT128 column.
+func scaledIntToBlr(param driver.Value, x *xSQLVAR) ([]byte, []byte, bool, error) {
+	d, ok := paramToDecimal(param)
+	if !ok {
+		return nil, nil, false, nil
+	}
+	scaled := d.Shift(int32(-x.sqlscale))
+	if !scaled.Equal(scaled.Truncate(0)) {
+		return nil, nil, true, fmt.Errorf("Value %v can not be stored with scale %d without loss of precision", param, -x.sqlscale)
+	}
+	n := scaled.BigInt()
+	if x.sqltype == SQL_TYPE_INT128 {
+		blr, v, err := _int128ToBlr(n, x.sqlscale)
 		if err != nil {
-			break
+			err = fmt.Errorf("Value %v overflows %s", param, x.typename())
 		}
-		i += BLOB_SEGMENT_SIZE
+		return blr, v, true, err
 	}
-	if err != nil {
-		p.resumeBuffer(buf)
-		return blobId, err
+	if !n.IsInt64() {
+		return nil, nil, true, fmt.Errorf("Value %v overflows %s", param, x.typename())
+	}
+	i64 := n.Int64()
+	switch x.sqltype {
+	case SQL_TYPE_SHORT:
+		if i64 < math.MinInt16 || i64 > math.MaxInt16 {
+			return nil, nil, true, fmt.Errorf("Value %v overflows %s", param, x.typename())
+		}
+		blr, v := _scaledInt32ToBlr(int32(i64), x.sqlscale)
+		return blr, v, true, nil
+	case SQL_TYPE_LONG:
+		if i64 < math.MinInt32 || i64 > math.MaxInt32 {
+			return nil, nil, true, fmt.Errorf("Value %v overflows %s", param, x.typename())
+		}
+		blr, v := _scaledInt32ToBlr(int32(i64), x.sqlscale)
+		return blr, v, true, nil
 	}
+	blr, v := _scaledInt64ToBlr(i64, x.sqlscale)
+	return blr, v, true, nil
+}
 
-	if err = p.opCloseBlob(blobHandle); err != nil {
-		return nil, err
+// paramToBlr converts a parameter to BLR and value by the described type of the parameter.
+// Parameters which have no specific conversion are converted by the type of the Go value.
+func (p *wireProtocol) paramToBlr(transHandle int32, param driver.Value, x *xSQLVAR) ([]byte, []byte, error) {
+	if x == nil || param == nil {
+		return p.valueToBlr(transHandle, param)
+	}
+
+	switch x.sqltype {
+	case SQL_TYPE_TEXT, SQL_TYPE_VARYING:
+		if s, ok := param.(string); ok {
+			b, err := encodeCharset(s, x.columnCharset(p.charset))
+			if err != nil {
+				return nil, nil, err
+			}
+			return p.valueToBlr(transHandle, b)
+		}
+	case SQL_TYPE_BLOB:
+		if s, ok := param.(string); ok && x.sqlsubtype == 1 {
+			b, err := encodeCharset(s, x.blobCharset(p.charset))
+			if err != nil {
+				return nil, nil, err
+			}
+			v, err := p.createBlob(b, transHandle)
+			return []byte{9, 0}, v, err
+		}
+	case SQL_TYPE_SHORT, SQL_TYPE_LONG, SQL_TYPE_INT64, SQL_TYPE_INT128:
+		blr, v, ok, err := scaledIntToBlr(param, x)
+		if ok {
+			return blr, v, err
+		}
+	case SQL_TYPE_DATE:
+		if t, ok := param.(time.Time); ok {
+			if p.dialect < 3 { // DATE of dialect 1 is a timestamp
+				blr, v := _timestampToBlr(t)
+				return blr, v, nil
+			}
+			blr, v := _dateToBlr(t)
+			return blr, v, nil
+		}
+	case SQL_TYPE_TIME:
+		if t, ok := param.(time.Time); ok {
+			blr, v := _timeToBlr(t)
+			return blr, v, nil
+		}
+	case SQL_TYPE_TIMESTAMP:
+		if t, ok := param.(time.Time); ok {
+			blr, v := _timestampToBlr(t)
+			return blr, v, nil
+		}
+	case SQL_TYPE_TIME_TZ:
+		if t, ok := param.(time.Time); ok {
+			return _timeTzToBlr(t)
+		}
+	case SQL_TYPE_TIMESTAMP_TZ:
+		if t, ok := param.(time.Time); ok {
+			return _timestampTzToBlr(t)
+		}
+	case SQL_TYPE_DEC64, SQL_TYPE_DEC128:
+		if d, ok := paramToDecFloat(param); ok {
+			return _decFloatToBlr(d, x.sqltype == SQL_TYPE_DEC64)
+		}
+	case SQL_TYPE_BOOLEAN:
+		switch f := param.(type) {
+		case int64:
+			return p.valueToBlr(transHandle, f != 0)
+		}
 	}
-	_, _, _, err = p.opResponse()
+	return p.valueToBlr(transHandle, param)
+}
 
-	p.resumeBuffer(buf)
-	return blobId, err
+// valueToBlr converts a parameter to BLR and value by the type of the Go value.
+func (p *wireProtocol) valueToBlr(transHandle int32, param driver.Value) (blr []byte, v []byte, err error) {
+	switch f := param.(type) {
+	case string:
+		b, err := encodeCharset(f, p.charset)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(b) < MAX_CHAR_LENGTH {
+			blr, v = _bytesToBlr(b)
+		} else {
+			v, err = p.createBlob(b, transHandle)
+			blr = []byte{9, 0}
+		}
+	case int:
+		blr, v = _int32ToBlr(int32(f))
+	case int16:
+		blr, v = _int32ToBlr(int32(f))
+	case int32:
+		blr, v = _int32ToBlr(f)
+	case int64:
+		if p.dialect < 3 {
+			// dialect 1 has no 64bit integer
+			if f >= math.MinInt32 && f <= math.MaxInt32 {
+				blr, v = _int32ToBlr(int32(f))
+			} else {
+				blr, v = _float64ToBlr(float64(f))
+			}
+		} else {
+			blr, v = _int64ToBlr(int64(f))
+		}
+	case uint:
+		return p.valueToBlr(transHandle, uint64(f))
+	case uint16:
+		blr, v = _int32ToBlr(int32(f))
+	case uint32:
+		return p.valueToBlr(transHandle, int64(f))
+	case uint64:
+		if f > math.MaxInt64 {
+			if p.protocolVersion < PROTOCOL_VERSION16 {
+				// INT128 is available since Firebird 4.0
+				return nil, nil, fmt.Errorf("Value %v overflows BIGINT", f)
+			}
+			return _int128ToBlr(new(big.Int).SetUint64(f), 0)
+		}
+		return p.valueToBlr(transHandle, int64(f))
+	case *big.Int:
+		if f == nil {
+			return p.valueToBlr(transHandle, nil)
+		}
+		if f.IsInt64() && p.protocolVersion < PROTOCOL_VERSION16 {
+			// INT128 is available since Firebird 4.0
+			return p.valueToBlr(transHandle, f.Int64())
+		}
+		return _int128ToBlr(f, 0)
+	case decimal.Decimal:
+		return _decimalToBlr(f)
+	case DecFloat:
+		return _decFloatToBlr(f, false)
+	case float32:
+		blr, v = _float32ToBlr(f)
+	case float64:
+		blr, v = _float64ToBlr(float64(f))
+	case time.Time:
+		// dialect 1 has no TIME, DATE is a timestamp
+		if f.Year() == 0 && p.dialect == 3 {
+			blr, v = _timeToBlr(f)
+		} else {
+			blr, v = _timestampToBlr(f)
+		}
+	case bool:
+		if f {
+			v = []byte{1, 0, 0, 0}
+		} else {
+			v = []byte{0, 0, 0, 0}
+		}
+		blr = []byte{23}
+	case nil:
+		v = []byte{}
+		blr = []byte{14, 0, 0}
+	case []byte:
+		if len(f) < MAX_CHAR_LENGTH {
+			blr, v = _bytesToBlr(f)
+		} else {
+			v, err = p.createBlob(f, transHandle)
+			blr = []byte{9, 0}
+		}
+	case arrayID:
+		v = f
+		blr = []byte{9, 0}
+	case *BlobWriter:
+		if !f.closed {
+			return nil, nil, ErrBlobWriterNotClosed
+		}
+		if f.blobId == nil {
+			// cancelled
+			return nil, nil, ErrBlobClosed
+		}
+		v = f.blobId
+		blr = []byte{9, 0}
+	case io.Reader:
+		v, err = p.createBlobFromReader(f, transHandle)
+		blr = []byte{9, 0}
+	default:
+		return nil, nil, fmt.Errorf("Unsupported parameter type %T", f)
+	}
+	return
 }
 
-func (p *wireProtocol) paramsToBlr(transHandle int32, params []driver.Value, protocolVersion int32) ([]byte, []byte) {
+func (p *wireProtocol) paramsToBlr(transHandle int32, params []driver.Value, paramXsqlda []xSQLVAR, protocolVersion int32) ([]byte, []byte, error) {
 	// Convert parameter array to BLR and values format.
 	var v, blr []byte
 	bi256 := big.NewInt(256)
@@ -1385,58 +1907,15 @@ func (p *wireProtocol) paramsToBlr(transHandle int32, params []driver.Value, pro
 		}
 	}
 
-	for _, param := range params {
-		switch f := param.(type) {
-		case string:
-			b := str_to_bytes(f)
-			if len(b) < MAX_CHAR_LENGTH {
-				blr, v = _bytesToBlr(b)
-			} else {
-				v, _ = p.createBlob(b, transHandle)
-				blr = []byte{9, 0}
-			}
-		case int:
-			blr, v = _int32ToBlr(int32(f))
-		case int16:
-			blr, v = _int32ToBlr(int32(f))
-		case int32:
-			blr, v = _int32ToBlr(f)
-		case int64:
-			blr, v = _int64ToBlr(int64(f))
-		case float64:
-			blr, v = _float64ToBlr(float64(f))
-		case time.Time:
-			if f.Year() == 0 {
-				blr, v = _timeToBlr(f)
-			} else {
-				blr, v = _timestampToBlr(f)
-			}
-		case bool:
-			if f {
-				v = []byte{1, 0, 0, 0}
-			} else {
-				v = []byte{0, 0, 0, 0}
-			}
-			blr = []byte{23}
-		case nil:
-			v = []byte{}
-			blr = []byte{14, 0, 0}
-		case []byte:
-			if len(f) < MAX_CHAR_LENGTH {
-				blr, v = _bytesToBlr(f)
-			} else {
-				v, _ = p.createBlob(f, transHandle)
-				blr = []byte{9, 0}
-			}
-		default:
-			// can't convert directory
-			b := str_to_bytes(fmt.Sprintf("%v", f))
-			if len(b) < MAX_CHAR_LENGTH {
-				blr, v = _bytesToBlr(b)
-			} else {
-				v, _ = p.createBlob(b, transHandle)
-				blr = []byte{9, 0}
-			}
+	for i, param := range params {
+		var x *xSQLVAR
+		if i < len(paramXsqlda) {
+			x = &paramXsqlda[i]
+		}
+		var err error
+		blr, v, err = p.paramToBlr(transHandle, param, x)
+		if err != nil {
+			return nil, nil, err
 		}
 		valuesList.PushBack(v)
 		if protocolVersion < PROTOCOL_VERSION13 {
@@ -1454,7 +1933,7 @@ func (p *wireProtocol) paramsToBlr(transHandle int32, params []driver.Value, pro
 	blr = flattenBytes(blrList)
 	v = flattenBytes(valuesList)
 
-	return blr, v
+	return blr, v, nil
 }
 
 func (p *wireProtocol) debugPrint(s string, a ...interface{}) {
@@ -0,0 +1,227 @@
+/*******************************************************************************
+The MIT License (MIT)
+
+Copyright (c) 2026 Hajime Nakagami
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*******************************************************************************/
+
+package firebirdsql
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseXsqlda(t *testing.T) {
+	buf := []byte{
+		isc_info_sql_stmt_type, 4, 0, isc_info_sql_stmt_select, 0, 0, 0,
+		isc_info_sql_select, isc_info_sql_describe_vars, 4, 0, 1, 0, 0, 0,
+		isc_info_sql_sqlda_seq, 4, 0, 1, 0, 0, 0,
+		isc_info_sql_type, 4, 0, 0xf0, 0x01, 0, 0, // SQL_TYPE_LONG
+		isc_info_sql_alias, 1, 0, 'A',
+		isc_info_sql_describe_end,
+		isc_info_sql_bind, isc_info_sql_describe_vars, 4, 0, 2, 0, 0, 0,
+		isc_info_sql_sqlda_seq, 4, 0, 1, 0, 0, 0,
+		isc_info_sql_type, 4, 0, 0xc5, 0x01, 0, 0, // SQL_TYPE_TEXT + 1
+		isc_info_sql_length, 4, 0, 10, 0, 0, 0,
+		isc_info_sql_describe_end,
+		isc_info_sql_sqlda_seq, 4, 0, 2, 0, 0, 0,
+		isc_info_sql_type, 4, 0, 0x44, 0x02, 0, 0, // SQL_TYPE_INT64
+		isc_info_sql_scale, 4, 0, 0xfe, 0xff, 0xff, 0xff,
+		isc_info_sql_describe_end,
+		isc_info_end,
+	}
+
+	p := &wireProtocol{}
+	stmtType, xsqlda, paramXsqlda, err := p.parse_xsqlda(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(isc_info_sql_stmt_select), stmtType)
+	require.Len(t, xsqlda, 1)
+	assert.Equal(t, SQL_TYPE_LONG, xsqlda[0].sqltype)
+	assert.Equal(t, "A", xsqlda[0].aliasname)
+	require.Len(t, paramXsqlda, 2)
+	assert.Equal(t, SQL_TYPE_TEXT, paramXsqlda[0].sqltype)
+	assert.Equal(t, 10, paramXsqlda[0].sqllen)
+	assert.Equal(t, SQL_TYPE_INT64, paramXsqlda[1].sqltype)
+	assert.Equal(t, -2, paramXsqlda[1].sqlscale)
+}
+
+func TestParamToBlr(t *testing.T) {
+	p := &wireProtocol{dialect: 3}
+	numeric := &xSQLVAR{sqltype: SQL_TYPE_INT64, sqlscale: -4}
+
+	blr, v, err := p.paramToBlr(0, 1234.5678, numeric)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{16, 0xfc}, blr)
+	assert.Equal(t, bint64_to_bytes(12345678), v)
+
+	blr, v, err = p.paramToBlr(0, "-0.5", numeric)
+	require.NoError(t, err)
+	assert.Equal(t, bint64_to_bytes(-5000), v)
+
+	_, _, err = p.paramToBlr(0, 1.23456, numeric)
+	assert.EqualError(t, err, "Value 1.23456 can not be stored with scale 4 without loss of precision")
+
+	_, _, err = p.paramToBlr(0, int64(40000), &xSQLVAR{sqltype: SQL_TYPE_SHORT})
+	assert.EqualError(t, err, "Value 40000 overflows SHORT")
+
+	blr, v, err = p.paramToBlr(0, true, &xSQLVAR{sqltype: SQL_TYPE_SHORT})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{8, 0}, blr)
+	assert.Equal(t, bint32_to_bytes(1), v)
+
+	d := time.Date(2024, 2, 29, 12, 34, 56, 0, time.UTC)
+	blr, v, err = p.paramToBlr(0, d, &xSQLVAR{sqltype: SQL_TYPE_DATE})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{12}, blr)
+	assert.Equal(t, _convert_date(d), v)
+
+	blr, _, err = p.paramToBlr(0, d, &xSQLVAR{sqltype: SQL_TYPE_TIME})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{13}, blr)
+
+	_, _, err = p.paramToBlr(0, struct{}{}, nil)
+	assert.EqualError(t, err, "Unsupported parameter type struct {}")
+}
+
+func TestValueToBlr(t *testing.T) {
+	p := &wireProtocol{dialect: 3, protocolVersion: PROTOCOL_VERSION16}
+
+	blr, v, err := p.valueToBlr(0, decimal.RequireFromString("-123.45"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{16, 0xfe}, blr)
+	assert.Equal(t, bint64_to_bytes(-12345), v)
+
+	blr, v, err = p.valueToBlr(0, decimal.RequireFromString("12345678901234567890.12"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{26, 0xfe}, blr)
+	assert.Len(t, v, 16)
+
+	blr, v, err = p.valueToBlr(0, big.NewInt(-1))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{26, 0}, blr)
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 16), v)
+
+	blr, v, err = p.valueToBlr(0, uint64(math.MaxUint64))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{26, 0}, blr)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, v)
+
+	blr, v, err = p.valueToBlr(0, uint32(7))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{16, 0}, blr)
+	assert.Equal(t, bint64_to_bytes(7), v)
+
+	blr, v, err = p.valueToBlr(0, float32(1.5))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{10}, blr)
+	assert.Equal(t, []byte{0x3f, 0xc0, 0, 0}, v)
+
+	_, err = bint128_to_bytes(new(big.Int).Lsh(big.NewInt(1), 127))
+	assert.Error(t, err)
+
+	_, _, err = p.paramToBlr(0, uint64(math.MaxUint64), &xSQLVAR{sqltype: SQL_TYPE_INT64})
+	assert.EqualError(t, err, "Value 18446744073709551615 overflows INT64")
+
+	p.protocolVersion = PROTOCOL_VERSION13
+	_, _, err = p.valueToBlr(0, uint64(math.MaxUint64))
+	assert.EqualError(t, err, "Value 18446744073709551615 overflows BIGINT")
+	blr, v, err = p.valueToBlr(0, uint64(math.MaxInt64))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{16, 0}, blr)
+	assert.Equal(t, bint64_to_bytes(math.MaxInt64), v)
+}
+
+func TestTimeZoneParamToBlr(t *testing.T) {
+	p := &wireProtocol{dialect: 3}
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	require.NoError(t, err)
+	ts := time.Date(1967, 8, 11, 23, 45, 1, 0, seoul)
+
+	blr, v, err := p.paramToBlr(0, ts, &xSQLVAR{sqltype: SQL_TYPE_TIMESTAMP_TZ})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{29}, blr)
+	require.Len(t, v, 12)
+	x := &xSQLVAR{sqltype: SQL_TYPE_TIMESTAMP_TZ}
+	decoded, err := x.parseTimestampTz(v)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(decoded))
+	assert.Equal(t, "Asia/Seoul", decoded.Location().String())
+
+	blr, v, err = p.paramToBlr(0, ts, &xSQLVAR{sqltype: SQL_TYPE_TIME_TZ})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{28}, blr)
+	require.Len(t, v, 8)
+	decoded, err = x.parseTimeTz(v)
+	require.NoError(t, err)
+	assert.Equal(t, "23:45:01", decoded.Format("15:04:05"))
+
+	// the offset of a region is resolved on 2020-01-01 in both directions
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	_, v, err = p.paramToBlr(0, time.Date(2024, 7, 1, 12, 0, 0, 0, berlin), &xSQLVAR{sqltype: SQL_TYPE_TIME_TZ})
+	require.NoError(t, err)
+	assert.Equal(t, _convert_time(time.Date(0, 1, 1, 11, 0, 0, 0, time.UTC)), v[:4])
+	decoded, err = x.parseTimeTz(v)
+	require.NoError(t, err)
+	assert.Equal(t, "12:00:00 +0100", decoded.Format("15:04:05 -0700"))
+
+	id, err := timezoneIDOf(time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("", -(5*60+30)*60)))
+	require.NoError(t, err)
+	assert.Equal(t, 1439-330, id)
+	id, err = timezoneIDOf(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", getTimezoneNameByID(id))
+}
+
+func TestFetchResponseValueError(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn, err := newWireChannel(client)
+	require.NoError(t, err)
+	p := &wireProtocol{conn: conn, protocolVersion: PROTOCOL_VERSION13}
+
+	response := bint32_to_bytes(op_fetch_response)
+	response = append(response, bint32_to_bytes(0)...) // status
+	response = append(response, bint32_to_bytes(1)...) // count
+	response = append(response, 0, 0, 0, 0)            // null indicators
+	// TIMESTAMP WITH TIME ZONE of the unknown zone id 30000
+	response = append(response, 0, 0, 0xe4, 0x63, 0, 0, 0, 0, 0, 0, 0x75, 0x30)
+	response = append(response, bint32_to_bytes(op_fetch_response)...)
+	response = append(response, bint32_to_bytes(100)...)
+	response = append(response, bint32_to_bytes(0)...)
+	go func() {
+		server.Write(response)
+		server.Close()
+	}()
+
+	xsqlda := []xSQLVAR{{sqltype: SQL_TYPE_TIMESTAMP_TZ}}
+	_, _, err = p.opFetchResponse(0, 0, xsqlda)
+	assert.EqualError(t, err, "Unknown time zone id 30000")
+	_, err = p.recvPackets(1)
+	assert.Error(t, err, "the whole response is read")
+}
@@ -26,12 +26,8 @@ package firebirdsql
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"github.com/shopspring/decimal"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/encoding/korean"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/encoding/traditionalchinese"
 	"math"
 	"math/big"
 	"reflect"
@@ -78,8 +74,8 @@ var xsqlvarTypeLength = map[int]int{
 	SQL_TYPE_QUAD:         8,
 	SQL_TYPE_INT64:        8,
 	SQL_TYPE_INT128:       16,
-	SQL_TYPE_TIMESTAMP_TZ: 10,
-	SQL_TYPE_TIME_TZ:      6,
+	SQL_TYPE_TIMESTAMP_TZ: 12,
+	SQL_TYPE_TIME_TZ:      8,
 	SQL_TYPE_DEC64:        8,
 	SQL_TYPE_DEC128:       16,
 	SQL_TYPE_DEC_FIXED:    16,
@@ -159,7 +155,7 @@ func (x *xSQLVAR) displayLength() int {
 }
 
 func (x *xSQLVAR) hasPrecisionScale() bool {
-	return (x.sqltype == SQL_TYPE_SHORT || x.sqltype == SQL_TYPE_LONG || x.sqltype == SQL_TYPE_QUAD || x.sqltype == SQL_TYPE_INT64 || x.sqltype == SQL_TYPE_INT128 || x.sqltype == SQL_TYPE_DEC64 || x.sqltype == SQL_TYPE_DEC128 || x.sqltype == SQL_TYPE_DEC_FIXED) && x.sqlscale != 0
+	return (x.sqltype == SQL_TYPE_SHORT || x.sqltype == SQL_TYPE_LONG || x.sqltype == SQL_TYPE_QUAD || x.sqltype == SQL_TYPE_INT64 || x.sqltype == SQL_TYPE_INT128 || x.sqltype == SQL_TYPE_DOUBLE || x.sqltype == SQL_TYPE_DEC64 || x.sqltype == SQL_TYPE_DEC128 || x.sqltype == SQL_TYPE_DEC_FIXED) && x.sqlscale != 0
 }
 
 func (x *xSQLVAR) typename() string {
@@ -188,6 +184,9 @@ func (x *xSQLVAR) scantype() reflect.Type {
 		}
 		return reflect.TypeOf(int64(0))
 	case SQL_TYPE_INT128:
+		if x.sqlscale < 0 {
+			return reflect.TypeOf(decimal.Decimal{})
+		}
 		return reflect.TypeOf(big.Int{})
 	case SQL_TYPE_DATE:
 		return reflect.TypeOf(time.Time{})
@@ -198,6 +197,10 @@ func (x *xSQLVAR) scantype() reflect.Type {
 	case SQL_TYPE_FLOAT:
 		return reflect.TypeOf(float32(0))
 	case SQL_TYPE_DOUBLE:
+		if x.sqlscale != 0 {
+			// dialect 1 NUMERIC/DECIMAL stored as DOUBLE PRECISION
+			return reflect.TypeOf(decimal.Decimal{})
+		}
 		return reflect.TypeOf(float64(0))
 	case SQL_TYPE_BOOLEAN:
 		return reflect.TypeOf(false)
@@ -217,10 +220,15 @@ func (x *xSQLVAR) scantype() reflect.Type {
 	return reflect.TypeOf(nil)
 }
 
-func (x *xSQLVAR) _parseTimezone(raw_value []byte) *time.Location {
-	timezone := getTimezoneNameByID(int(bytes_to_buint16(raw_value)))
-	tz, _ := time.LoadLocation(timezone)
-	return tz
+func (x *xSQLVAR) _parseTimezone(raw_value []byte) (*time.Location, error) {
+	return getTimezoneByID(int(bytes_to_buint16(raw_value)))
+}
+
+func sessionTimezone(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	return loadTimezone(timezone)
 }
 
 func (x *xSQLVAR) _parseDate(raw_value []byte) (int, int, int) {
@@ -256,164 +264,94 @@ func (x *xSQLVAR) _parseTime(raw_value []byte) (int, int, int, int) {
 	return h, m, s, (n % 10000) * 100000
 }
 
-func (x *xSQLVAR) parseDate(raw_value []byte, timezone string) time.Time {
-	tz := time.Local
-	if timezone != "" {
-		tz, _ = time.LoadLocation(timezone)
+func (x *xSQLVAR) parseDate(raw_value []byte, timezone string) (time.Time, error) {
+	tz, err := sessionTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
 	}
 	year, month, day := x._parseDate(raw_value)
-	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, tz)
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, tz), nil
 }
 
-func (x *xSQLVAR) parseTime(raw_value []byte, timezone string) time.Time {
-	tz := time.Local
-	if timezone != "" {
-		tz, _ = time.LoadLocation(timezone)
+func (x *xSQLVAR) parseTime(raw_value []byte, timezone string) (time.Time, error) {
+	tz, err := sessionTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
 	}
 	h, m, s, n := x._parseTime(raw_value)
 	now := time.Now()
 	zone, offset := time.Date(now.Year(), now.Month(), now.Day(), h, m, s, n, tz).Zone()
-	return time.Date(0, time.Month(1), 1, h, m, s, n, time.FixedZone(zone, offset))
+	return time.Date(0, time.Month(1), 1, h, m, s, n, time.FixedZone(zone, offset)), nil
 }
 
-func (x *xSQLVAR) parseTimestamp(raw_value []byte, timezone string) time.Time {
-	tz := time.Local
-	if timezone != "" {
-		tz, _ = time.LoadLocation(timezone)
+func (x *xSQLVAR) parseTimestamp(raw_value []byte, timezone string) (time.Time, error) {
+	tz, err := sessionTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
 	}
 
 	year, month, day := x._parseDate(raw_value[:4])
 	h, m, s, n := x._parseTime(raw_value[4:8])
-	return time.Date(year, time.Month(month), day, h, m, s, n, tz)
+	return time.Date(year, time.Month(month), day, h, m, s, n, tz), nil
 }
 
-func (x *xSQLVAR) parseTimeTz(raw_value []byte) time.Time {
+// The time of TIME/TIMESTAMP WITH TIME ZONE is UTC, followed by the time zone id
+// as a sign extended 4 bytes short.
+
+func (x *xSQLVAR) parseTimeTz(raw_value []byte) (time.Time, error) {
+	if len(raw_value) < 8 {
+		return time.Time{}, fmt.Errorf("Invalid TIME WITH TIME ZONE length %d", len(raw_value))
+	}
 	h, m, s, n := x._parseTime(raw_value[:4])
-	tz := x._parseTimezone(raw_value[4:6])
-	loc := x._parseTimezone(raw_value[6:8])
-	now := time.Now()
-	t := time.Date(now.Year(), now.Month(), now.Day(), h, m, s, n, tz).In(loc)
+	loc, err := x._parseTimezone(raw_value[6:8])
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := timeTzOnBaseDate(h, m, s, n, time.UTC).In(loc)
 	zone, offset := t.Zone()
-	return time.Date(0, time.Month(1), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(zone, offset))
+	return time.Date(0, time.Month(1), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(zone, offset)), nil
 }
 
-func (x *xSQLVAR) parseTimestampTz(raw_value []byte) time.Time {
+func (x *xSQLVAR) parseTimestampTz(raw_value []byte) (time.Time, error) {
+	if len(raw_value) < 12 {
+		return time.Time{}, fmt.Errorf("Invalid TIMESTAMP WITH TIME ZONE length %d", len(raw_value))
+	}
 	year, month, day := x._parseDate(raw_value[:4])
 	h, m, s, n := x._parseTime(raw_value[4:8])
-	tz := x._parseTimezone(raw_value[8:10])
-	offset := x._parseTimezone(raw_value[10:12])
-	return time.Date(year, time.Month(month), day, h, m, s, n, tz).In(offset)
-}
-
-func (x *xSQLVAR) decode(rawValue []byte, charset string) []byte {
-	var decoded []byte
-	switch charset {
-	case "SJIS_0208":
-		dec := japanese.ShiftJIS.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "EUCJ_0208":
-		dec := japanese.EUCJP.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_1":
-		dec := charmap.ISO8859_1.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_2":
-		dec := charmap.ISO8859_2.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_3":
-		dec := charmap.ISO8859_3.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_4":
-		dec := charmap.ISO8859_5.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_5":
-		dec := charmap.ISO8859_5.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_6":
-		dec := charmap.ISO8859_6.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_7":
-		dec := charmap.ISO8859_7.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_8":
-		dec := charmap.ISO8859_8.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_9":
-		dec := charmap.ISO8859_9.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "ISO8859_13":
-		dec := charmap.ISO8859_13.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "KSC_5601":
-		dec := korean.EUCKR.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1250":
-		dec := charmap.Windows1250.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1251":
-		dec := charmap.Windows1251.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1252":
-		dec := charmap.Windows1252.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1253":
-		dec := charmap.Windows1252.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1254":
-		dec := charmap.Windows1252.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "BIG_5":
-		dec := traditionalchinese.Big5.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "GB_2312":
-		dec := simplifiedchinese.HZGB2312.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1255":
-		dec := charmap.Windows1255.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1256":
-		dec := charmap.Windows1256.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1257":
-		dec := charmap.Windows1257.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "KOI8R":
-		dec := charmap.KOI8R.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "KOI8U":
-		dec := charmap.KOI8U.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "WIN1258":
-		dec := charmap.Windows1258.NewDecoder()
-		decoded, _ = dec.Bytes(rawValue)
-	case "UNICODE_FSS", "UTF8":
-		fallthrough
-	default:
-		decoded = rawValue
+	loc, err := x._parseTimezone(raw_value[10:12])
+	if err != nil {
+		return time.Time{}, err
 	}
+	return time.Date(year, time.Month(month), day, h, m, s, n, time.UTC).In(loc), nil
+}
 
-	return decoded
+func (x *xSQLVAR) decode(rawValue []byte, charset string) ([]byte, error) {
+	return decodeCharset(rawValue, x.columnCharset(charset))
 }
 
 func (x *xSQLVAR) value(raw_value []byte, timezone string, charset string) (v interface{}, err error) {
 	switch x.sqltype {
 	case SQL_TYPE_TEXT:
-		realLen := x.sqllen
-		if x.sqlsubtype == 4 || x.sqlsubtype == 69 { // UTF8 and GB18030
-			realLen = x.sqllen / 4
-		} else if x.sqlsubtype == 3 { // UNICODE_FSS
-			realLen = x.sqllen / 3
-		}
 		if x.sqlsubtype == 1 || charset == "OCTETS" { //OCTETS
-			v = raw_value[:realLen]
+			v = raw_value[:x.sqllen]
 		} else {
-			v = string(x.decode(raw_value, charset)[:realLen])
+			// sqllen is the length in bytes of the maximum characters
+			n := x.sqllen / charsetMaxBytesPerChar(x.sqlsubtype&0xff)
+			var decoded []byte
+			if decoded, err = x.decode(raw_value, charset); err != nil {
+				return nil, err
+			}
+			runes := []rune(string(decoded))
+			if len(runes) > n {
+				runes = runes[:n]
+			}
+			v = string(runes)
 		}
 	case SQL_TYPE_VARYING:
 		if x.sqlsubtype == 1 || charset == "OCTETS" { //OCTETS
 			v = raw_value
 		} else {
-			v = x.decode(raw_value, charset)
+			v, err = x.decode(raw_value, charset)
 		}
 	case SQL_TYPE_SHORT:
 		i16 := int16(bytes_to_bint32(raw_value))
@@ -443,63 +381,24 @@ func (x *xSQLVAR) value(raw_value []byte, timezone string, charset string) (v in
 			v = i64
 		}
 	case SQL_TYPE_INT128:
-		var isNegative bool
-
-		// when raw_value[0] is > 127, then subtract 255 in every index
-		if raw_value[0] > 127 {
-			for i := range raw_value {
-				if raw_value[i] < 255 {
-					raw_value[i] = 255 - raw_value[i]
-				} else {
-					raw_value[i] -= 255
-				}
-			}
-			isNegative = true
-		}
-
-		// reverse
-		for i, j := 0, len(raw_value)-1; i < j; i, j = i+1, j-1 {
-			raw_value[i], raw_value[j] = raw_value[j], raw_value[i]
-		}
-
-		// variable to return
-		var x = new(big.Int)
-
-		for i := len(raw_value) - 1; i >= 0; i-- {
-			if raw_value[i] == 0 {
-				continue
-			}
-
-			// get the 2^(i*8) in big.Float
-			var t = new(big.Float).SetFloat64(math.Pow(2, float64(i*8)))
-
-			// convert the float to int
-			var xx *big.Int
-			xx, _ = t.Int(xx)
-
-			// mul with the value in raw_value
-			xx.Mul(xx, big.NewInt(int64(raw_value[i])))
-
-			// add to x
-			x.Add(x, xx)
-		}
-
-		// when negative, add 1 and mul -1
-		if isNegative {
-			x.Add(x, big.NewInt(1))
-			x.Mul(x, big.NewInt(-1))
+		i128 := bytes_to_bint128(raw_value)
+		if x.sqlscale > 0 {
+			v = i128.Mul(i128, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(x.sqlscale)), nil))
+		} else if x.sqlscale < 0 {
+			v = decimal.NewFromBigInt(i128, int32(x.sqlscale))
+		} else {
+			v = i128
 		}
-		v = x
 	case SQL_TYPE_DATE:
-		v = x.parseDate(raw_value, timezone)
+		v, err = x.parseDate(raw_value, timezone)
 	case SQL_TYPE_TIME:
-		v = x.parseTime(raw_value, timezone)
+		v, err = x.parseTime(raw_value, timezone)
 	case SQL_TYPE_TIMESTAMP:
-		v = x.parseTimestamp(raw_value, timezone)
+		v, err = x.parseTimestamp(raw_value, timezone)
 	case SQL_TYPE_TIME_TZ:
-		v = x.parseTimeTz(raw_value)
+		v, err = x.parseTimeTz(raw_value)
 	case SQL_TYPE_TIMESTAMP_TZ:
-		v = x.parseTimestampTz(raw_value)
+		v, err = x.parseTimestampTz(raw_value)
 	case SQL_TYPE_FLOAT:
 		var f32 float32
 		b := bytes.NewReader(raw_value)
@@ -509,17 +408,22 @@ func (x *xSQLVAR) value(raw_value []byte, timezone string, charset string) (v in
 		b := bytes.NewReader(raw_value)
 		var f64 float64
 		err = binary.Read(b, binary.BigEndian, &f64)
-		v = f64
+		if x.sqlscale != 0 && err == nil {
+			// dialect 1 NUMERIC/DECIMAL stored as DOUBLE PRECISION
+			v = decimal.NewFromFloat(f64).Round(int32(-x.sqlscale))
+		} else {
+			v = f64
+		}
 	case SQL_TYPE_BOOLEAN:
 		v = raw_value[0] != 0
-	case SQL_TYPE_BLOB:
+	case SQL_TYPE_BLOB, SQL_TYPE_ARRAY:
 		v = raw_value
 	case SQL_TYPE_DEC_FIXED:
 		v = decimalFixedToDecimal(raw_value, int32(x.sqlscale))
 	case SQL_TYPE_DEC64:
-		v = decimal64ToDecimal(raw_value)
+		v = decimal64ToDecFloat(raw_value).driverValue()
 	case SQL_TYPE_DEC128:
-		v = decimal128ToDecimal(raw_value)
+		v = decimal128ToDecFloat(raw_value).driverValue()
 	}
 	return
 }